@@ -7,7 +7,15 @@ import (
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/controllers/aiven_application"
 	"github.com/nais/aivenator/controllers/secrets"
+	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/aiven/quota"
+	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/credentials"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
+	"github.com/nais/aivenator/pkg/handlers/opensearch"
+	"github.com/nais/aivenator/pkg/handlers/redis"
+	"github.com/nais/aivenator/pkg/handlers/secret"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
@@ -27,6 +35,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "net/http/pprof" // Enable http profiling
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -41,14 +50,38 @@ const (
 
 // Configuration options
 const (
-	AivenToken                   = "aiven-token"
-	KubernetesWriteRetryInterval = "kubernetes-write-retry-interval"
-	LogFormat                    = "log-format"
-	LogLevel                     = "log-level"
-	MetricsAddress               = "metrics-address"
-	Projects                     = "projects"
-	SyncPeriod                   = "sync-period"
-	MainProject                  = "main-project"
+	AivenToken                     = "aiven-token"
+	KubernetesWriteRetryInterval   = "kubernetes-write-retry-interval"
+	LogFormat                      = "log-format"
+	LogLevel                       = "log-level"
+	MetricsAddress                 = "metrics-address"
+	Projects                       = "projects"
+	SyncPeriod                     = "sync-period"
+	MainProject                    = "main-project"
+	KafkaClientConfigMountPath     = "kafka-client-config-mount-path"
+	KafkaCertificateReissue        = "kafka-certificate-reissue-window"
+	KafkaQuotaConsumerByteRate     = "kafka-quota-consumer-byte-rate"
+	KafkaQuotaProducerByteRate     = "kafka-quota-producer-byte-rate"
+	KafkaQuotaRequestPercentage    = "kafka-quota-request-percentage"
+	AddressRoutes                  = "address-routes"
+	ProjectAddressRoutes           = "project-address-routes"
+	PreviousCAOverlap              = "previous-ca-overlap"
+	ExtraCAPath                    = "extra-ca-path"
+	CACacheExpiration              = "ca-cache-expiration"
+	ClusterName                    = "cluster-name"
+	KafkaServiceUserSuffixLength   = "kafka-service-user-suffix-length"
+	KafkaMigrateLegacyUsers        = "kafka-migrate-legacy-users"
+	KafkaMigrationInterval         = "kafka-migration-interval"
+	AdoptLegacySecrets             = "adopt-legacy-secrets"
+	AdoptLegacySecretsDryRun       = "adopt-legacy-secrets-dry-run"
+	OpenSearchIndexPatterns        = "opensearch-index-patterns"
+	OpenSearchDefaultIndexPatterns = "opensearch-default-index-patterns"
+	OpenSearchPerApplicationUsers  = "opensearch-per-application-users"
+	AccessPolicyPath               = "access-policy-path"
+	RedisApplicationPatterns       = "redis-application-patterns"
+	RedisUnrestrictedUsers         = "redis-unrestricted-users"
+	ServiceNameTemplates           = "service-name-templates"
+	ServiceNameMappings            = "service-name-mappings"
 )
 
 const (
@@ -70,8 +103,32 @@ func init() {
 	flag.String(LogLevel, "info", logLevelHelp())
 	flag.Duration(KubernetesWriteRetryInterval, time.Second*10, "Requeueing interval when Kubernetes writes fail")
 	flag.Duration(SyncPeriod, time.Hour*1, "How often to re-synchronize all AivenApplication resources including credential rotation")
+	flag.String(ClusterName, "", "Name of the cluster aivenator runs in, used to keep service user names unique across clusters")
 	flag.StringSlice(Projects, []string{"nav-integration-test"}, "List of projects allowed to operate on")
 	flag.String(MainProject, "nav-integration-test", "Main project to operate on for services that only allow one")
+	flag.Duration(KafkaCertificateReissue, time.Hour*24*30, "Replace Kafka service users with new ones when their certificate expires within this window, 0 to disable")
+	flag.String(KafkaClientConfigMountPath, kafka.DefaultClientConfigMountPath, "Path where applications mount the Kafka credentials, used in generated client configuration files")
+	flag.Int(KafkaServiceUserSuffixLength, kafka.DefaultServiceUserSuffixLength, fmt.Sprintf("Number of characters in the suffix of Kafka service user names, between %d and %d, shorter for long team and application names", kafka.MinServiceUserSuffixLength, kafka.MaxServiceUserSuffixLength))
+	flag.Bool(KafkaMigrateLegacyUsers, false, "Replace Kafka service users with legacy names by users in the current naming convention")
+	flag.Duration(KafkaMigrationInterval, time.Minute*5, "Minimum time between two migrations of Kafka service users in the same pool")
+	flag.Bool(AdoptLegacySecrets, false, "Backfill aivenator metadata on Kafka secrets created by kafkarator, so their service users are cleaned up")
+	flag.Bool(AdoptLegacySecretsDryRun, false, "Only log the metadata that would be backfilled on Kafka secrets created by kafkarator")
+	flag.Float64(KafkaQuotaConsumerByteRate, 0, "Default consumer byte rate quota for Kafka service users, 0 for unlimited")
+	flag.Float64(KafkaQuotaProducerByteRate, 0, "Default producer byte rate quota for Kafka service users, 0 for unlimited")
+	flag.Float64(KafkaQuotaRequestPercentage, 0, "Default request percentage quota for Kafka service users, 0 for unlimited")
+	flag.StringSlice(OpenSearchDefaultIndexPatterns, opensearch.DefaultIndexPatterns, "Index patterns OpenSearch service users get access to, for namespaces without specific patterns")
+	flag.StringToString(OpenSearchIndexPatterns, map[string]string{}, "Index patterns OpenSearch service users in specific namespaces get access to, as namespace=pattern;pattern. Applications with their own service user may override them with the opensearch.aiven.nais.io/indexPatterns annotation")
+	flag.Bool(OpenSearchPerApplicationUsers, false, "Give each application its own OpenSearch service user, instead of sharing one per namespace and access level. Shared users are deleted once no secret uses them")
+	flag.Bool(RedisApplicationPatterns, false, "Limit Redis service users without key and channel annotations to keys and channels prefixed with the application name, instead of all keys and channels. Existing service users are narrowed as well. Access to all keys and channels by default is deprecated")
+	flag.Bool(RedisUnrestrictedUsers, false, "Allow service users without access control on Dragonfly services, where aivenator does not support it")
+	flag.StringToString(ServiceNameTemplates, map[string]string{}, "Go templates for the Aiven service names of redis, opensearch and influxdb instances, as type=template, over .Namespace, .Application and .Instance. Redis users of services not named by the default template get the namespace in their names")
+	flag.StringToString(ServiceNameMappings, map[string]string{}, "Aiven service names for specific instances, as type/namespace/instance=service, taking precedence over templates")
+	flag.String(AccessPolicyPath, "", "YAML file with the instances namespaces may get credentials for, typically a mounted ConfigMap, empty to allow all")
+	flag.StringSlice(AddressRoutes, []string{}, "Preferred routes for service addresses, most preferred first (dynamic, private, privatelink, public)")
+	flag.Duration(CACacheExpiration, time.Hour*1, "How long project CAs are cached, they are refreshed in the background at half this interval")
+	flag.Duration(PreviousCAOverlap, time.Hour*24*7, "How long clients keep trusting the previous project CA after a rotation, 0 to disable")
+	flag.String(ExtraCAPath, "", "Directory with additional PEM encoded CAs to trust, typically a mounted ConfigMap")
+	flag.StringToString(ProjectAddressRoutes, map[string]string{}, "Preferred routes for service addresses in specific projects, as project=route;route")
 
 	flag.Parse()
 
@@ -79,6 +136,11 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	// NAIS_CLUSTER_NAME is set by the platform, and was used before the option existed
+	err = viper.BindEnv(ClusterName, "AIVENATOR_CLUSTER_NAME", "NAIS_CLUSTER_NAME")
+	if err != nil {
+		panic(err)
+	}
 }
 
 func logLevelHelp() string {
@@ -215,8 +277,72 @@ func newAivenClient(ctx context.Context, logger log.FieldLogger) (*aiven.Client,
 func manageCredentials(ctx context.Context, aiven *aiven.Client, logger *log.Logger, mgr manager.Manager, projects []string, mainProjectName string, aivenv1 *aivenv1.Client) error {
 	appChanges := make(chan aiven_nais_io_v1.AivenApplication)
 
-	credentialsManager := credentials.NewManager(ctx, aiven, projects, mainProjectName, logger.WithFields(log.Fields{"component": "CredentialsManager"}), aivenv1)
-	reconciler := aiven_application.NewReconciler(mgr, logger, credentialsManager, appChanges)
+	kafkaConfig := kafka.Config{
+		ClientConfigMountPath:    viper.GetString(KafkaClientConfigMountPath),
+		CertificateReissueWindow: viper.GetDuration(KafkaCertificateReissue),
+		Quota: quota.Quota{
+			ConsumerByteRate:  viper.GetFloat64(KafkaQuotaConsumerByteRate),
+			ProducerByteRate:  viper.GetFloat64(KafkaQuotaProducerByteRate),
+			RequestPercentage: viper.GetFloat64(KafkaQuotaRequestPercentage),
+		},
+		ClusterName:             viper.GetString(ClusterName),
+		ServiceUserSuffixLength: viper.GetInt(KafkaServiceUserSuffixLength),
+		Migration: kafka.MigrationConfig{
+			Enabled:  viper.GetBool(KafkaMigrateLegacyUsers),
+			Interval: viper.GetDuration(KafkaMigrationInterval),
+		},
+	}
+	if err := kafkaConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid Kafka configuration: %w", err)
+	}
+	addressRoutes := service.AddressRoutes{
+		Default:  viper.GetStringSlice(AddressRoutes),
+		Projects: make(map[string][]string),
+	}
+	for project, routes := range viper.GetStringMapString(ProjectAddressRoutes) {
+		addressRoutes.Projects[project] = strings.Split(routes, ";")
+	}
+	openSearchConfig := opensearch.Config{
+		DefaultIndexPatterns: viper.GetStringSlice(OpenSearchDefaultIndexPatterns),
+		IndexPatterns:        make(map[string][]string),
+		PerApplicationUsers:  viper.GetBool(OpenSearchPerApplicationUsers),
+	}
+	for namespace, patterns := range viper.GetStringMapString(OpenSearchIndexPatterns) {
+		openSearchConfig.IndexPatterns[namespace] = strings.Split(patterns, ";")
+	}
+	redisConfig := redis.Config{
+		ApplicationPatterns: viper.GetBool(RedisApplicationPatterns),
+		UnrestrictedUsers:   viper.GetBool(RedisUnrestrictedUsers),
+	}
+	names, err := service.NewTemplateNameResolver(service.NameConfig{
+		Templates: viper.GetStringMapString(ServiceNameTemplates),
+		Mappings:  viper.GetStringMapString(ServiceNameMappings),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid service name configuration: %w", err)
+	}
+	var authorizer policy.Authorizer = policy.AllowAll{}
+	if path := viper.GetString(AccessPolicyPath); path != "" {
+		fileAuthorizer := policy.NewFileAuthorizer(path)
+		if _, err := fileAuthorizer.Load(); err != nil {
+			return err
+		}
+		authorizer = fileAuthorizer
+	}
+	caChanges := make(chan project.CAChange, 10)
+	caBundler := project.NewCABundler(project.CAConfig{
+		PreviousCAOverlap: viper.GetDuration(PreviousCAOverlap),
+		ExtraCAPath:       viper.GetString(ExtraCAPath),
+	}, caChanges)
+	caCacheExpiration := viper.GetDuration(CACacheExpiration)
+	if caCacheExpiration < time.Second {
+		return fmt.Errorf("invalid CA cache expiration %v, must be at least 1s", caCacheExpiration)
+	}
+	projectManager := project.NewCachedManager(ctx, project.NewManager(aiven.CA), caCacheExpiration, logger.WithFields(log.Fields{"component": "ProjectManager"}))
+	projectManager.StartRefresher(ctx)
+	credentialsManager := credentials.NewManager(ctx, aiven, projectManager, projects, mainProjectName, kafkaConfig, openSearchConfig, redisConfig, addressRoutes, names, caBundler, mgr.GetClient(), authorizer, logger.WithFields(log.Fields{"component": "CredentialsManager"}), aivenv1)
+	resync := make(chan event.GenericEvent)
+	reconciler := aiven_application.NewReconciler(mgr, logger, credentialsManager, appChanges, resync)
 
 	if err := reconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to set up reconciler: %s", err)
@@ -246,6 +372,26 @@ func manageCredentials(ctx context.Context, aiven *aiven.Client, logger *log.Log
 	}
 	logger.Info("Aiven Secret janitor setup complete")
 
+	if viper.GetBool(AdoptLegacySecrets) {
+		adopter := secrets.SecretsAdopter{
+			Logger:   logger.WithFields(log.Fields{"component": "SecretsAdopter"}),
+			Client:   mgr.GetClient(),
+			Projects: projects,
+			DryRun:   viper.GetBool(AdoptLegacySecretsDryRun),
+		}
+		if err := adopter.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to set up secrets adopter: %s", err)
+		}
+		logger.Info("Aiven Secret adopter setup complete")
+	}
+
+	fingerprintAnnotations := []string{secret.CAFingerprintAnnotation, kafka.CAFingerprintAnnotation}
+	caResyncer := aiven_application.NewCAResyncer(mgr.GetClient(), caChanges, resync, fingerprintAnnotations, logger.WithFields(log.Fields{"component": "CAResyncer"}))
+	if err := mgr.Add(caResyncer); err != nil {
+		return fmt.Errorf("unable to add CA resyncer to manager: %v", err)
+	}
+	logger.Info("CA resyncer setup complete")
+
 	return nil
 }
 
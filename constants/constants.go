@@ -11,6 +11,7 @@ const (
 	AivenatorProtectedWithTimeLimitAnnotation = "aivenator.aiven.nais.io/with-time-limit"
 	AivenatorProtectedExpiresAtAnnotation     = "aivenator.aiven.nais.io/expires-at"
 	AivenatorRetryCounterAnnotation           = "aivenator.aiven.nais.io/retries"
+	AivenatorRenewAfterAnnotation             = "aivenator.aiven.nais.io/renew-after"
 
 	AivenatorSecretType = "aivenator.aiven.nais.io"
 )
@@ -0,0 +1,129 @@
+package aiven_application
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// CAResyncer marks secrets containing a rotated CA for renewal, and requeues the AivenApplications owning them
+type CAResyncer struct {
+	client.Client
+	logger    log.FieldLogger
+	caChanges <-chan project.CAChange
+	resync    chan<- event.GenericEvent
+	handled   map[project.CAChange]bool
+	// fingerprintAnnotations are the annotations handlers record CA fingerprints in, possibly prefixed by a pool name
+	fingerprintAnnotations []string
+}
+
+func NewCAResyncer(c client.Client, caChanges <-chan project.CAChange, resync chan<- event.GenericEvent, fingerprintAnnotations []string, logger log.FieldLogger) *CAResyncer {
+	return &CAResyncer{
+		Client:                 c,
+		logger:                 logger,
+		caChanges:              caChanges,
+		resync:                 resync,
+		handled:                make(map[project.CAChange]bool),
+		fingerprintAnnotations: fingerprintAnnotations,
+	}
+}
+
+func (c *CAResyncer) Start(ctx context.Context) error {
+	for {
+		select {
+		case change := <-c.caChanges:
+			// Every application with the old CA reports the same change while it is being resynced
+			if c.handled[change] {
+				continue
+			}
+			c.handled[change] = true
+			c.logger.Infof("CA for project %s rotated, resynchronizing affected applications", change.Project)
+			err := c.resyncAffected(ctx, change)
+			if err != nil {
+				c.logger.Errorf("unable to resynchronize applications after CA rotation: %v", err)
+				delete(c.handled, change)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *CAResyncer) resyncAffected(ctx context.Context, change project.CAChange) error {
+	var secrets corev1.SecretList
+	err := metrics.ObserveKubernetesLatency("Secret_List", func() error {
+		return c.List(ctx, &secrets, client.MatchingLabels{
+			constants.SecretTypeLabel: constants.AivenatorSecretType,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	affected := make(map[client.ObjectKey]bool)
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !c.hasFingerprint(secret, change.OldFingerprint) {
+			continue
+		}
+		secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+			constants.AivenatorRenewAfterAnnotation: time.Now().Format(time.RFC3339),
+		}))
+		err = metrics.ObserveKubernetesLatency("Secret_Update", func() error {
+			return c.Update(ctx, secret)
+		})
+		if err != nil {
+			c.logger.Warnf("unable to mark secret %s/%s for renewal: %v", secret.GetNamespace(), secret.GetName(), err)
+			continue
+		}
+		affected[client.ObjectKeyFromObject(secret)] = true
+	}
+
+	var applications aiven_nais_io_v1.AivenApplicationList
+	err = metrics.ObserveKubernetesLatency("AivenApplication_List", func() error {
+		return c.List(ctx, &applications)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range applications.Items {
+		application := &applications.Items[i]
+		if !affected[application.SecretKey()] {
+			continue
+		}
+		select {
+		case c.resync <- event.GenericEvent{Object: application}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	c.logger.Infof("Marked %d secrets for renewal after CA rotation in project %s", len(affected), change.Project)
+	return nil
+}
+
+// hasFingerprint tells if one of the CA fingerprint annotations of the secret, for any pool, has the fingerprint
+func (c *CAResyncer) hasFingerprint(secret *corev1.Secret, fingerprint string) bool {
+	for key, value := range secret.GetAnnotations() {
+		if value != fingerprint {
+			continue
+		}
+		for _, annotation := range c.fingerprintAnnotations {
+			if key == annotation || strings.HasSuffix(key, "."+annotation) {
+				return true
+			}
+		}
+	}
+	return false
+}
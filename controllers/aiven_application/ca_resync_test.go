@@ -0,0 +1,22 @@
+package aiven_application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCAResyncer_hasFingerprint(t *testing.T) {
+	const fingerprint = "0123456789abcdef"
+	resyncer := CAResyncer{fingerprintAnnotations: []string{"kafka.aiven.nais.io/caFingerprint"}}
+	secret := func(key string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{key: fingerprint}}}
+	}
+
+	assert.True(t, resyncer.hasFingerprint(secret("kafka.aiven.nais.io/caFingerprint"), fingerprint))
+	assert.True(t, resyncer.hasFingerprint(secret("other-pool.kafka.aiven.nais.io/caFingerprint"), fingerprint))
+	assert.False(t, resyncer.hasFingerprint(secret("kafka.aiven.nais.io/caFingerprint"), "fedcba9876543210"))
+	assert.False(t, resyncer.hasFingerprint(secret("kafka.aiven.nais.io/serviceUser"), fingerprint))
+}
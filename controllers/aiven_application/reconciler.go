@@ -16,9 +16,13 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/pkg/credentials"
 	"github.com/nais/aivenator/pkg/metrics"
 	"github.com/nais/aivenator/pkg/utils"
@@ -32,12 +36,13 @@ const (
 	AivenVolumeName    = "aiven-credentials"
 )
 
-func NewReconciler(mgr manager.Manager, logger *log.Logger, credentialsManager credentials.Manager, appChanges chan<- aiven_nais_io_v1.AivenApplication) AivenApplicationReconciler {
+func NewReconciler(mgr manager.Manager, logger *log.Logger, credentialsManager credentials.Manager, appChanges chan<- aiven_nais_io_v1.AivenApplication, resync <-chan event.GenericEvent) AivenApplicationReconciler {
 	return AivenApplicationReconciler{
 		Client:     mgr.GetClient(),
 		Logger:     logger.WithFields(log.Fields{"component": "AivenApplicationReconciler"}),
 		Manager:    credentialsManager,
 		appChanges: appChanges,
+		resync:     resync,
 	}
 }
 
@@ -46,6 +51,7 @@ type AivenApplicationReconciler struct {
 	Logger     *log.Entry
 	Manager    credentials.Manager
 	appChanges chan<- aiven_nais_io_v1.AivenApplication
+	resync     <-chan event.GenericEvent
 }
 
 func (r *AivenApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -129,14 +135,14 @@ func (r *AivenApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return fail(err)
 	}
 
-	needsSync, err := r.NeedsSynchronization(ctx, application, hash, logger)
+	needsSync, result, err := r.NeedsSynchronization(ctx, application, hash, logger)
 	if err != nil {
 		utils.LocalFail("NeedsSynchronization", &application, err, logger)
 		return fail(err)
 	}
 
 	if !needsSync {
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	processingStart := time.Now()
@@ -168,7 +174,16 @@ func (r *AivenApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	success(&application, hash)
 
-	return ctrl.Result{}, nil
+	return renewal(secret), nil
+}
+
+// renewal schedules the next synchronization for when the secret is due for renewal, since nothing else changes then
+func renewal(secret *corev1.Secret) ctrl.Result {
+	renewAfter, err := utils.Parse(secret.GetAnnotations()[constants.AivenatorRenewAfterAnnotation])
+	if err != nil {
+		return ctrl.Result{}
+	}
+	return ctrl.Result{RequeueAfter: time.Until(renewAfter)}
 }
 
 func (r *AivenApplicationReconciler) initSecret(ctx context.Context, application aiven_nais_io_v1.AivenApplication, logger log.FieldLogger) *corev1.Secret {
@@ -239,21 +254,28 @@ func success(application *aiven_nais_io_v1.AivenApplication, hash string) {
 		Type:   aiven_nais_io_v1.AivenApplicationLocalFailure,
 		Status: corev1.ConditionFalse,
 	})
+	s.AddCondition(aiven_nais_io_v1.AivenApplicationCondition{
+		Type:   utils.AivenApplicationPolicyDenied,
+		Status: corev1.ConditionFalse,
+	})
 }
 
 func (r *AivenApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	opts := controller.Options{
 		MaxConcurrentReconciles: 10,
 	}
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&aiven_nais_io_v1.AivenApplication{}).
 		WithOptions(opts).
 		WithEventFilter(predicate.Or(
 			predicate.GenerationChangedPredicate{},
 			predicate.AnnotationChangedPredicate{},
 			predicate.LabelChangedPredicate{},
-		)).
-		Complete(r)
+		))
+	if r.resync != nil {
+		builder = builder.WatchesRawSource(&source.Channel{Source: r.resync}, &handler.EnqueueRequestForObject{})
+	}
+	return builder.Complete(r)
 }
 
 func (r *AivenApplicationReconciler) SaveSecret(ctx context.Context, secret *corev1.Secret, logger *log.Entry) error {
@@ -295,11 +317,11 @@ func (r *AivenApplicationReconciler) SaveSecret(ctx context.Context, secret *cor
 	return err
 }
 
-func (r *AivenApplicationReconciler) NeedsSynchronization(ctx context.Context, application aiven_nais_io_v1.AivenApplication, hash string, logger *log.Entry) (bool, error) {
+func (r *AivenApplicationReconciler) NeedsSynchronization(ctx context.Context, application aiven_nais_io_v1.AivenApplication, hash string, logger *log.Entry) (bool, ctrl.Result, error) {
 	if application.Status.SynchronizationHash != hash {
 		logger.Infof("Hash changed; needs synchronization")
 		metrics.ProcessingReason.WithLabelValues(metrics.HashChanged.String()).Inc()
-		return true, nil
+		return true, ctrl.Result{}, nil
 	}
 
 	old := corev1.Secret{}
@@ -308,11 +330,22 @@ func (r *AivenApplicationReconciler) NeedsSynchronization(ctx context.Context, a
 	case k8serrors.IsNotFound(err):
 		logger.Infof("Secret not found; needs synchronization")
 		metrics.ProcessingReason.WithLabelValues(metrics.MissingSecret.String()).Inc()
-		return true, nil
+		return true, ctrl.Result{}, nil
 	case err != nil:
-		return false, fmt.Errorf("unable to retrieve secret from cluster: %s", err)
+		return false, ctrl.Result{}, fmt.Errorf("unable to retrieve secret from cluster: %s", err)
+	}
+
+	if renewAfter, ok := old.GetAnnotations()[constants.AivenatorRenewAfterAnnotation]; ok {
+		parsedTimeStamp, err := utils.Parse(renewAfter)
+		if err != nil {
+			logger.Warnf("unable to parse %s annotation: %s", constants.AivenatorRenewAfterAnnotation, err)
+		} else if utils.Expired(parsedTimeStamp) {
+			logger.Infof("Secret due for renewal; needs synchronization")
+			metrics.ProcessingReason.WithLabelValues(metrics.RenewalDue.String()).Inc()
+			return true, ctrl.Result{}, nil
+		}
 	}
 
 	logger.Infof("Already synchronized")
-	return false, nil
+	return false, renewal(&old), nil
 }
@@ -71,12 +71,14 @@ func TestAivenApplicationReconciler_NeedsSynchronization(t *testing.T) {
 		application aiven_nais_io_v1.AivenApplication
 		hasSecret   bool
 		isProtected bool
+		renewAfter  string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    bool
-		wantErr bool
+		name        string
+		args        args
+		want        bool
+		wantRequeue bool
+		wantErr     bool
 	}{
 		{
 			name: "EmptyApplication",
@@ -136,6 +138,35 @@ func TestAivenApplicationReconciler_NeedsSynchronization(t *testing.T) {
 			want:    true,
 			wantErr: false,
 		},
+		{
+			name: "UnchangedApplicationDueForRenewal",
+			args: args{
+				application: aiven_nais_io_v1.NewAivenApplicationBuilder(appName, namespace).
+					WithSpec(aiven_nais_io_v1.AivenApplicationSpec{SecretName: secretName}).
+					WithStatus(aiven_nais_io_v1.AivenApplicationStatus{SynchronizationHash: syncHash}).
+					Build(),
+				hasSecret:   true,
+				isProtected: false,
+				renewAfter:  time.Now().Add(-time.Hour).Format(time.RFC3339),
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: "UnchangedApplicationNotDueForRenewal",
+			args: args{
+				application: aiven_nais_io_v1.NewAivenApplicationBuilder(appName, namespace).
+					WithSpec(aiven_nais_io_v1.AivenApplicationSpec{SecretName: secretName}).
+					WithStatus(aiven_nais_io_v1.AivenApplicationStatus{SynchronizationHash: syncHash}).
+					Build(),
+				hasSecret:   true,
+				isProtected: false,
+				renewAfter:  time.Now().Add(time.Hour).Format(time.RFC3339),
+			},
+			want:        false,
+			wantRequeue: true,
+			wantErr:     false,
+		},
 		{
 			name: "ProtectedApplication",
 			args: args{
@@ -162,6 +193,9 @@ func TestAivenApplicationReconciler_NeedsSynchronization(t *testing.T) {
 				if tt.args.isProtected {
 					annotations[constants.AivenatorProtectedAnnotation] = "true"
 				}
+				if tt.args.renewAfter != "" {
+					annotations[constants.AivenatorRenewAfterAnnotation] = tt.args.renewAfter
+				}
 				clientBuilder.WithRuntimeObjects(&corev1.Secret{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:            secretName,
@@ -182,7 +216,7 @@ func TestAivenApplicationReconciler_NeedsSynchronization(t *testing.T) {
 				t.Errorf("Failed to generate hash: %s", err)
 				return
 			}
-			got, err := r.NeedsSynchronization(ctx, tt.args.application, hash, r.Logger)
+			got, result, err := r.NeedsSynchronization(ctx, tt.args.application, hash, r.Logger)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NeedsSynchronization() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -190,6 +224,9 @@ func TestAivenApplicationReconciler_NeedsSynchronization(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("NeedsSynchronization() got = %v, want %v; actual hash: %v", got, tt.want, hash)
 			}
+			if tt.wantRequeue != (result.RequeueAfter > 0) {
+				t.Errorf("NeedsSynchronization() requeue after = %v, want requeue %v", result.RequeueAfter, tt.wantRequeue)
+			}
 		})
 	}
 }
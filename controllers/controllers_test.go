@@ -10,7 +10,13 @@ import (
 	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/controllers/aiven_application"
 	"github.com/nais/aivenator/controllers/secrets"
+	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/credentials"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
+	"github.com/nais/aivenator/pkg/handlers/opensearch"
+	"github.com/nais/aivenator/pkg/handlers/redis"
+	"github.com/nais/aivenator/pkg/policy"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	"github.com/nais/liberator/pkg/crd"
@@ -123,9 +129,9 @@ func newTestRig(ctx context.Context, t *testing.T, logger *log.Logger) (*testRig
 		return nil, fmt.Errorf("unable to set up aivenv1 client: %s", err)
 	}
 
-	credentialsManager := credentials.NewManager(ctx, aivenClient, []string{testProject}, testProject, logger.WithField("component", "CredentialsManager"), aivenv1Client)
+	credentialsManager := credentials.NewManager(ctx, aivenClient, project.NewManager(aivenClient.CA), []string{testProject}, testProject, kafka.Config{}, opensearch.Config{}, redis.Config{}, service.AddressRoutes{}, service.NewDefaultNameResolver(), project.NewCABundler(project.CAConfig{}, nil), rig.client, policy.AllowAll{}, logger.WithField("component", "CredentialsManager"), aivenv1Client)
 	appChanges := make(chan aiven_nais_io_v1.AivenApplication)
-	reconciler := aiven_application.NewReconciler(rig.manager, logger, credentialsManager, appChanges)
+	reconciler := aiven_application.NewReconciler(rig.manager, logger, credentialsManager, appChanges, nil)
 
 	err = reconciler.SetupWithManager(rig.manager)
 	if err != nil {
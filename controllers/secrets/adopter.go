@@ -0,0 +1,192 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+// Annotations set on secrets created by kafkarator, before aivenator took over
+const (
+	KafkaratorPoolAnnotation        = "kafka.nais.io/pool"
+	KafkaratorApplicationAnnotation = "kafka.nais.io/application"
+)
+
+// legacySecretName matches kafka-<app>-<pool>-<hash>, the secret names used by kafkarator
+var legacySecretName = regexp.MustCompile(`^kafka-(.+)-[0-9a-f]{7,8}$`)
+
+// SecretsAdopter backfills the metadata aivenator relies on to older Kafka secrets, so their service users are cleaned up
+// along with them. Every change is written to the audit log.
+type SecretsAdopter struct {
+	client.Client
+	Logger   *log.Entry
+	Projects []string
+	// DryRun only logs the changes that would have been made
+	DryRun bool
+}
+
+// adoption is a change to the metadata of a secret
+type adoption struct {
+	kind  string
+	key   string
+	value string
+}
+
+func (s *SecretsAdopter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var secret v1.Secret
+
+	logger := s.Logger.WithFields(log.Fields{
+		"secret_name": req.Name,
+		"namespace":   req.Namespace,
+	})
+
+	err := s.Get(ctx, req.NamespacedName, &secret)
+	switch {
+	case errors.IsNotFound(err):
+		return ctrl.Result{}, nil
+	case err != nil:
+		logger.Errorf("Unable to retrieve resource from cluster: %s", err)
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+
+	changes, err := s.adopt(&secret)
+	if err != nil {
+		logger.Warnf("Unable to adopt secret: %v", err)
+		return ctrl.Result{}, nil
+	}
+	if len(changes) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	if !s.DryRun {
+		err = metrics.ObserveKubernetesLatency("Secret_Update", func() error {
+			return s.Update(ctx, &secret)
+		})
+		if err != nil {
+			logger.Errorf("Unable to save adopted secret: %v", err)
+			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+		}
+	}
+
+	for _, change := range changes {
+		logger.WithFields(log.Fields{
+			"audit":   true,
+			"dry_run": s.DryRun,
+			"kind":    change.kind,
+			"key":     change.key,
+			"value":   change.value,
+		}).Infof("Adopted secret: set %s %s", change.kind, change.key)
+	}
+	return ctrl.Result{}, nil
+}
+
+// adopt fills in missing metadata on a legacy Kafka secret, and returns the changes made
+func (s *SecretsAdopter) adopt(secret *v1.Secret) ([]adoption, error) {
+	appName, pool, ok := s.matchLegacySecret(secret)
+	if !ok {
+		return nil, nil
+	}
+
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	labels := secret.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	serviceUserName := annotations[kafka.ServiceUserAnnotation]
+	if serviceUserName == "" {
+		serviceUserName = string(secret.Data[kafka.KafkaSchemaUser])
+	}
+	if serviceUserName == "" {
+		return nil, fmt.Errorf("unable to determine service user, neither %s annotation nor %s key is set", kafka.ServiceUserAnnotation, kafka.KafkaSchemaUser)
+	}
+	if value, ok := annotations[KafkaratorPoolAnnotation]; ok {
+		pool = value
+	}
+	if value, ok := annotations[KafkaratorApplicationAnnotation]; ok {
+		appName = value
+	}
+
+	var changes []adoption
+	set := func(kind string, values map[string]string, key, value string) {
+		if _, ok := values[key]; ok {
+			return
+		}
+		values[key] = value
+		changes = append(changes, adoption{kind: kind, key: key, value: value})
+	}
+	set("annotation", annotations, kafka.ServiceUserAnnotation, serviceUserName)
+	set("annotation", annotations, kafka.PoolAnnotation, pool)
+	set("label", labels, constants.SecretTypeLabel, constants.AivenatorSecretType)
+	set("label", labels, constants.AppLabel, appName)
+	if controllerutil.AddFinalizer(secret, constants.AivenatorFinalizer) {
+		changes = append(changes, adoption{kind: "finalizer", key: constants.AivenatorFinalizer})
+	}
+
+	secret.SetAnnotations(annotations)
+	secret.SetLabels(labels)
+	return changes, nil
+}
+
+// matchLegacySecret recognizes legacy Kafka secrets by name and contents, and returns the application and pool in the name
+func (s *SecretsAdopter) matchLegacySecret(secret *v1.Secret) (string, string, bool) {
+	if !secret.GetDeletionTimestamp().IsZero() {
+		return "", "", false
+	}
+	if _, ok := secret.Data[kafka.KafkaBrokers]; !ok {
+		return "", "", false
+	}
+	match := legacySecretName.FindStringSubmatch(secret.GetName())
+	if match == nil {
+		return "", "", false
+	}
+	// Both application and pool names contain dashes, so look for known pools at the end of the name
+	for _, pool := range s.Projects {
+		appName, found := strings.CutSuffix(match[1], "-"+pool)
+		if found && appName != "" {
+			return appName, pool, true
+		}
+	}
+	return "", "", false
+}
+
+func (s *SecretsAdopter) SetupWithManager(mgr ctrl.Manager) error {
+	legacy := func(object client.Object) bool {
+		return legacySecretName.MatchString(object.GetName()) && !controllerutil.ContainsFinalizer(object, constants.AivenatorFinalizer)
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secret-adopter").
+		For(&v1.Secret{}).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc: func(createEvent event.CreateEvent) bool {
+				return legacy(createEvent.Object)
+			},
+			DeleteFunc: func(event event.DeleteEvent) bool {
+				return false
+			},
+			UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+				return legacy(updateEvent.ObjectNew)
+			},
+			GenericFunc: func(genericEvent event.GenericEvent) bool {
+				return false
+			},
+		}).
+		Complete(s)
+}
@@ -0,0 +1,132 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
+)
+
+const (
+	legacyNamespace   = "team-a"
+	legacySecret      = "kafka-my-app-nav-dev-1a2b3c4d"
+	legacyServiceUser = "team-a.my-app"
+)
+
+func legacyKafkaSecret(name string, annotations map[string]string, data map[string][]byte) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   legacyNamespace,
+			Annotations: annotations,
+		},
+		Data: data,
+	}
+}
+
+func TestSecretsAdopter_adopt(t *testing.T) {
+	adopter := SecretsAdopter{Projects: []string{"nav-dev", "nav-prod"}}
+	kafkaData := map[string][]byte{
+		kafka.KafkaBrokers:    []byte("broker:1234"),
+		kafka.KafkaSchemaUser: []byte(legacyServiceUser),
+	}
+
+	tests := []struct {
+		name        string
+		secret      *v1.Secret
+		wantChanges int
+		wantApp     string
+		wantPool    string
+		wantErr     bool
+	}{
+		{
+			name:        "legacy secret",
+			secret:      legacyKafkaSecret(legacySecret, nil, kafkaData),
+			wantChanges: 5,
+			wantApp:     "my-app",
+			wantPool:    "nav-dev",
+		},
+		{
+			name: "kafkarator annotations take precedence over the name",
+			secret: legacyKafkaSecret(legacySecret, map[string]string{
+				KafkaratorApplicationAnnotation: "other-app",
+				KafkaratorPoolAnnotation:        "nav-prod",
+			}, kafkaData),
+			wantChanges: 5,
+			wantApp:     "other-app",
+			wantPool:    "nav-prod",
+		},
+		{
+			name:   "unknown pool",
+			secret: legacyKafkaSecret("kafka-my-app-nav-other-1a2b3c4d", nil, kafkaData),
+		},
+		{
+			name:   "not a Kafka secret",
+			secret: legacyKafkaSecret(legacySecret, nil, map[string][]byte{"password": []byte("secret")}),
+		},
+		{
+			name:    "no service user",
+			secret:  legacyKafkaSecret(legacySecret, nil, map[string][]byte{kafka.KafkaBrokers: []byte("broker:1234")}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := adopter.adopt(tt.secret)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, changes, tt.wantChanges)
+			if tt.wantChanges == 0 {
+				return
+			}
+			assert.Equal(t, legacyServiceUser, tt.secret.GetAnnotations()[kafka.ServiceUserAnnotation])
+			assert.Equal(t, tt.wantPool, tt.secret.GetAnnotations()[kafka.PoolAnnotation])
+			assert.Equal(t, tt.wantApp, tt.secret.GetLabels()[constants.AppLabel])
+			assert.Equal(t, constants.AivenatorSecretType, tt.secret.GetLabels()[constants.SecretTypeLabel])
+			assert.Contains(t, tt.secret.GetFinalizers(), constants.AivenatorFinalizer)
+
+			changes, err = adopter.adopt(tt.secret)
+			assert.NoError(t, err)
+			assert.Empty(t, changes)
+		})
+	}
+}
+
+func TestSecretsAdopter_Reconcile(t *testing.T) {
+	data := map[string][]byte{
+		kafka.KafkaBrokers:    []byte("broker:1234"),
+		kafka.KafkaSchemaUser: []byte(legacyServiceUser),
+	}
+	key := types.NamespacedName{Name: legacySecret, Namespace: legacyNamespace}
+
+	for _, dryRun := range []bool{false, true} {
+		client := fake.NewClientBuilder().WithObjects(legacyKafkaSecret(legacySecret, nil, data)).Build()
+		adopter := SecretsAdopter{
+			Client:   client,
+			Logger:   log.NewEntry(log.New()),
+			Projects: []string{"nav-dev"},
+			DryRun:   dryRun,
+		}
+
+		result, err := adopter.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+
+		var secret v1.Secret
+		assert.NoError(t, client.Get(context.Background(), key, &secret))
+		assert.Equal(t, !dryRun, secret.GetAnnotations()[kafka.ServiceUserAnnotation] == legacyServiceUser)
+		assert.Equal(t, !dryRun, len(secret.GetFinalizers()) > 0)
+	}
+}
@@ -0,0 +1,185 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// CAConfig decides which CAs clients trust in addition to the current project CA
+type CAConfig struct {
+	// PreviousCAOverlap is how long the previous project CA is trusted after a rotation, zero disables it
+	PreviousCAOverlap time.Duration
+	// ExtraCAPath is a directory, typically a mounted ConfigMap, where every file holds PEM encoded CAs to trust
+	ExtraCAPath string
+}
+
+// CAChange is sent when a project CA differs from the one last written to a secret
+type CAChange struct {
+	Project        string
+	OldFingerprint string
+	NewFingerprint string
+}
+
+// CAAnnotations are the secret annotations used to follow the CA of a project across rotations
+type CAAnnotations struct {
+	Fingerprint   string
+	Previous      string
+	PreviousUntil string
+}
+
+type CABundler struct {
+	config  CAConfig
+	changes chan<- CAChange
+}
+
+func NewCABundler(config CAConfig, changes chan<- CAChange) CABundler {
+	return CABundler{
+		config:  config,
+		changes: changes,
+	}
+}
+
+// Bundle returns the PEM bundle of CAs to trust for a project, with the current CA first.
+// The previous CA is taken from the first certificate of existingBundle, the bundle written to the secret last time.
+func (b CABundler) Bundle(projectName, currentCA, existingBundle string, annotations CAAnnotations, secret *v1.Secret, logger log.FieldLogger) string {
+	fingerprint := Fingerprint(currentCA)
+	recorded := secret.GetAnnotations()[annotations.Fingerprint]
+
+	if recorded != "" && recorded != fingerprint {
+		logger.Infof("CA for project %s changed from %s to %s", projectName, recorded, fingerprint)
+		b.notify(CAChange{
+			Project:        projectName,
+			OldFingerprint: recorded,
+			NewFingerprint: fingerprint,
+		}, logger)
+		if previous := firstCertificate(existingBundle); b.config.PreviousCAOverlap > 0 && previous != "" {
+			secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+				annotations.Previous:      previous,
+				annotations.PreviousUntil: time.Now().Add(b.config.PreviousCAOverlap).Format(time.RFC3339),
+			}))
+		}
+	}
+
+	cas := []string{currentCA}
+	previous, keep := b.previousCA(annotations, secret)
+	if keep {
+		cas = append(cas, previous)
+	} else {
+		delete(secret.GetAnnotations(), annotations.Previous)
+		delete(secret.GetAnnotations(), annotations.PreviousUntil)
+	}
+	cas = append(cas, b.extraCAs(logger)...)
+
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		annotations.Fingerprint: fingerprint,
+	}))
+
+	return joinCertificates(cas)
+}
+
+func (b CABundler) previousCA(annotations CAAnnotations, secret *v1.Secret) (string, bool) {
+	previous, ok := secret.GetAnnotations()[annotations.Previous]
+	if !ok || b.config.PreviousCAOverlap <= 0 {
+		return "", false
+	}
+	until, err := utils.Parse(secret.GetAnnotations()[annotations.PreviousUntil])
+	if err != nil || utils.Expired(until) {
+		return "", false
+	}
+	return previous, true
+}
+
+func (b CABundler) extraCAs(logger log.FieldLogger) []string {
+	if b.config.ExtraCAPath == "" {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(b.config.ExtraCAPath, "*"))
+	if err != nil {
+		logger.Warnf("unable to list extra CAs in %s: %v", b.config.ExtraCAPath, err)
+		return nil
+	}
+	cas := make([]string, 0, len(files))
+	for _, file := range files {
+		// ConfigMap mounts contain hidden directories and symlinks for atomic updates, only read the visible files
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() || strings.HasPrefix(filepath.Base(file), ".") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Warnf("unable to read extra CA %s: %v", file, err)
+			continue
+		}
+		cas = append(cas, string(data))
+	}
+	return cas
+}
+
+func (b CABundler) notify(change CAChange, logger log.FieldLogger) {
+	if b.changes == nil {
+		return
+	}
+	select {
+	case b.changes <- change:
+	default:
+		logger.Warnf("CA change for project %s not sent, a resync is already pending", change.Project)
+	}
+}
+
+// Fingerprint is the SHA-256 fingerprint of the first certificate in a PEM bundle
+func Fingerprint(bundle string) string {
+	data := []byte(strings.TrimSpace(bundle))
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func firstCertificate(bundle string) string {
+	block, _ := pem.Decode([]byte(bundle))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return ""
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+// joinCertificates concatenates PEM bundles, skipping certificates already included
+func joinCertificates(bundles []string) string {
+	seen := make(map[string]bool)
+	builder := strings.Builder{}
+	for _, bundle := range bundles {
+		rest := []byte(bundle)
+		found := false
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			found = true
+			fingerprint := fmt.Sprintf("%x", sha256.Sum256(block.Bytes))
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			builder.Write(pem.EncodeToMemory(block))
+		}
+		// Keep anything that is not PEM as is, so a malformed CA is passed on rather than silently dropped
+		if !found && strings.TrimSpace(bundle) != "" {
+			builder.WriteString(bundle)
+		}
+	}
+	return builder.String()
+}
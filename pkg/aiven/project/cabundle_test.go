@@ -0,0 +1,83 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/aivenator/pkg/certificate/certtest"
+)
+
+var testAnnotations = CAAnnotations{
+	Fingerprint:   "test/fingerprint",
+	Previous:      "test/previous",
+	PreviousUntil: "test/previousUntil",
+}
+
+func makeCA(t *testing.T, commonName string) string {
+	return certtest.SelfSigned(t, commonName, time.Now().Add(time.Hour)).Certificate
+}
+
+func TestBundleRecordsFingerprint(t *testing.T) {
+	ca := makeCA(t, "ca")
+	secret := &v1.Secret{}
+
+	bundle := NewCABundler(CAConfig{}, nil).Bundle("project", ca, "", testAnnotations, secret, log.New())
+
+	assert.Equal(t, ca, bundle)
+	assert.Equal(t, map[string]string{testAnnotations.Fingerprint: Fingerprint(ca)}, secret.GetAnnotations())
+}
+
+func TestBundleKeepsPreviousCA(t *testing.T) {
+	previous := makeCA(t, "previous")
+	current := makeCA(t, "current")
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{testAnnotations.Fingerprint: Fingerprint(previous)},
+		},
+	}
+	changes := make(chan CAChange, 1)
+
+	bundle := NewCABundler(CAConfig{PreviousCAOverlap: time.Hour}, changes).Bundle("project", current, previous, testAnnotations, secret, log.New())
+
+	assert.Equal(t, current+previous, bundle)
+	assert.Equal(t, Fingerprint(current), secret.GetAnnotations()[testAnnotations.Fingerprint])
+	assert.Equal(t, previous, secret.GetAnnotations()[testAnnotations.Previous])
+	assert.Equal(t, CAChange{
+		Project:        "project",
+		OldFingerprint: Fingerprint(previous),
+		NewFingerprint: Fingerprint(current),
+	}, <-changes)
+
+	// The previous CA is dropped once the overlap has passed
+	secret.Annotations[testAnnotations.PreviousUntil] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	bundle = NewCABundler(CAConfig{PreviousCAOverlap: time.Hour}, changes).Bundle("project", current, bundle, testAnnotations, secret, log.New())
+
+	assert.Equal(t, current, bundle)
+	assert.NotContains(t, secret.GetAnnotations(), testAnnotations.Previous)
+	assert.NotContains(t, secret.GetAnnotations(), testAnnotations.PreviousUntil)
+	assert.Empty(t, changes)
+}
+
+func TestBundleExtraCAs(t *testing.T) {
+	ca := makeCA(t, "ca")
+	extra := makeCA(t, "extra")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "extra.pem"), []byte(extra), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte(makeCA(t, "hidden")), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "duplicate.pem"), []byte(ca), 0o600))
+
+	bundle := NewCABundler(CAConfig{ExtraCAPath: dir}, nil).Bundle("project", ca, "", testAnnotations, &v1.Secret{}, log.New())
+
+	assert.Equal(t, 2, strings.Count(bundle, "BEGIN CERTIFICATE"))
+	assert.True(t, strings.HasPrefix(bundle, ca))
+	assert.Contains(t, bundle, extra)
+}
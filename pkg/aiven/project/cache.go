@@ -0,0 +1,115 @@
+package project
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cache "github.com/Code-Hex/go-generics-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+const (
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+// CachedManager keeps project CAs for a while, since they rarely change but are needed on every synchronization
+type CachedManager struct {
+	ProjectManager
+	expiration time.Duration
+	logger     log.FieldLogger
+	caCache    *cache.Cache[string, string]
+
+	lock         sync.Mutex
+	fingerprints map[string]string
+}
+
+func NewCachedManager(ctx context.Context, manager ProjectManager, expiration time.Duration, logger log.FieldLogger) *CachedManager {
+	return &CachedManager{
+		ProjectManager: manager,
+		expiration:     expiration,
+		logger:         logger,
+		caCache:        cache.NewContext[string, string](ctx),
+		fingerprints:   make(map[string]string),
+	}
+}
+
+func (m *CachedManager) GetCA(ctx context.Context, projectName string) (string, error) {
+	if ca, found := m.caCache.Get(projectName); found {
+		metrics.ProjectCACacheRequests.With(prometheus.Labels{metrics.LabelPool: projectName, metrics.LabelResult: cacheHit}).Inc()
+		return ca, nil
+	}
+	metrics.ProjectCACacheRequests.With(prometheus.Labels{metrics.LabelPool: projectName, metrics.LabelResult: cacheMiss}).Inc()
+	return m.refresh(ctx, projectName)
+}
+
+// StartRefresher refreshes known project CAs before they expire, so synchronizations rarely wait for Aiven
+func (m *CachedManager) StartRefresher(ctx context.Context) {
+	go m.refreshAll(ctx)
+}
+
+func (m *CachedManager) refreshAll(ctx context.Context) {
+	interval := m.expiration / 2
+	if interval <= 0 {
+		m.logger.Warnf("CA cache expiration %v is too short to refresh CAs in the background", m.expiration)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, projectName := range m.knownProjects() {
+				// On failure the cached CA is kept until it expires, giving Aiven time to recover
+				if _, err := m.refresh(ctx, projectName); err != nil {
+					m.logger.Warnf("unable to refresh CA for project %s: %v", projectName, err)
+				}
+			}
+		}
+	}
+}
+
+func (m *CachedManager) refresh(ctx context.Context, projectName string) (string, error) {
+	ca, err := m.ProjectManager.GetCA(ctx, projectName)
+	if err != nil {
+		return "", err
+	}
+	m.caCache.Set(projectName, ca, cache.WithExpiration(m.expiration))
+	m.observeFingerprint(projectName, Fingerprint(ca))
+	return ca, nil
+}
+
+func (m *CachedManager) observeFingerprint(projectName, fingerprint string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	previous, known := m.fingerprints[projectName]
+	if known && previous == fingerprint {
+		return
+	}
+	if known {
+		m.logger.Infof("CA for project %s changed from %s to %s", projectName, previous, fingerprint)
+		metrics.ProjectCAChanges.With(prometheus.Labels{metrics.LabelPool: projectName}).Inc()
+		metrics.ProjectCAInfo.Delete(prometheus.Labels{metrics.LabelPool: projectName, metrics.LabelFingerprint: previous})
+	}
+	metrics.ProjectCAInfo.With(prometheus.Labels{metrics.LabelPool: projectName, metrics.LabelFingerprint: fingerprint}).Set(1)
+	m.fingerprints[projectName] = fingerprint
+}
+
+func (m *CachedManager) knownProjects() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	projects := make([]string, 0, len(m.fingerprints))
+	for projectName := range m.fingerprints {
+		projects = append(projects, projectName)
+	}
+	return projects
+}
@@ -0,0 +1,84 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+func TestCachedManagerCachesCA(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ca := makeCA(t, "ca")
+	projects := NewMockProjectManager(t)
+	projects.On("GetCA", mock.Anything, "cached").Return(ca, nil).Once()
+
+	manager := NewCachedManager(ctx, projects, time.Hour, log.New())
+	for i := 0; i < 3; i++ {
+		got, err := manager.GetCA(ctx, "cached")
+		assert.NoError(t, err)
+		assert.Equal(t, ca, got)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.ProjectCACacheRequests.WithLabelValues("cached", cacheHit)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProjectCAInfo.WithLabelValues("cached", Fingerprint(ca))))
+}
+
+func TestCachedManagerDetectsChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	previous := makeCA(t, "previous")
+	current := makeCA(t, "current")
+	projects := NewMockProjectManager(t)
+	projects.On("GetCA", mock.Anything, "rotated").Return(previous, nil).Once()
+	projects.On("GetCA", mock.Anything, "rotated").Return(current, nil).Once()
+
+	manager := NewCachedManager(ctx, projects, time.Hour, log.New())
+	_, err := manager.GetCA(ctx, "rotated")
+	assert.NoError(t, err)
+	got, err := manager.refresh(ctx, "rotated")
+	assert.NoError(t, err)
+	assert.Equal(t, current, got)
+
+	got, err = manager.GetCA(ctx, "rotated")
+	assert.NoError(t, err)
+	assert.Equal(t, current, got)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProjectCAChanges.WithLabelValues("rotated")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProjectCAInfo.WithLabelValues("rotated", Fingerprint(current))))
+	// The series for the previous fingerprint was removed when the change was detected
+	assert.False(t, metrics.ProjectCAInfo.DeleteLabelValues("rotated", Fingerprint(previous)))
+}
+
+func TestCachedManagerDoesNotCacheErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ca := makeCA(t, "ca")
+	projects := NewMockProjectManager(t)
+	projects.On("GetCA", mock.Anything, "failing").Return("", errors.New("unavailable")).Once()
+	projects.On("GetCA", mock.Anything, "failing").Return(ca, nil).Once()
+
+	manager := NewCachedManager(ctx, projects, time.Hour, log.New())
+	_, err := manager.GetCA(ctx, "failing")
+	assert.Error(t, err)
+	got, err := manager.GetCA(ctx, "failing")
+	assert.NoError(t, err)
+	assert.Equal(t, ca, got)
+}
+
+func TestCachedManagerRefresherWithoutInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager := NewCachedManager(ctx, NewMockProjectManager(t), time.Nanosecond, log.New())
+	assert.NotPanics(t, func() {
+		manager.refreshAll(ctx)
+	})
+}
@@ -0,0 +1,196 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package quota
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockQuotaManager is an autogenerated mock type for the QuotaManager type
+type MockQuotaManager struct {
+	mock.Mock
+}
+
+type MockQuotaManager_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockQuotaManager) EXPECT() *MockQuotaManager_Expecter {
+	return &MockQuotaManager_Expecter{mock: &_m.Mock}
+}
+
+// Delete provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName
+func (_m *MockQuotaManager) Delete(ctx context.Context, serviceUserName string, projectName string, serviceName string) error {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockQuotaManager_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type MockQuotaManager_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+func (_e *MockQuotaManager_Expecter) Delete(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}) *MockQuotaManager_Delete_Call {
+	return &MockQuotaManager_Delete_Call{Call: _e.mock.On("Delete", ctx, serviceUserName, projectName, serviceName)}
+}
+
+func (_c *MockQuotaManager_Delete_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string)) *MockQuotaManager_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockQuotaManager_Delete_Call) Return(_a0 error) *MockQuotaManager_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockQuotaManager_Delete_Call) RunAndReturn(run func(context.Context, string, string, string) error) *MockQuotaManager_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName
+func (_m *MockQuotaManager) Get(ctx context.Context, serviceUserName string, projectName string, serviceName string) (*Quota, error) {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *Quota
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*Quota, error)); ok {
+		return rf(ctx, serviceUserName, projectName, serviceName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *Quota); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Quota)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, serviceUserName, projectName, serviceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockQuotaManager_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type MockQuotaManager_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+func (_e *MockQuotaManager_Expecter) Get(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}) *MockQuotaManager_Get_Call {
+	return &MockQuotaManager_Get_Call{Call: _e.mock.On("Get", ctx, serviceUserName, projectName, serviceName)}
+}
+
+func (_c *MockQuotaManager_Get_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string)) *MockQuotaManager_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockQuotaManager_Get_Call) Return(_a0 *Quota, _a1 error) *MockQuotaManager_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockQuotaManager_Get_Call) RunAndReturn(run func(context.Context, string, string, string) (*Quota, error)) *MockQuotaManager_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Set provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName, _a4
+func (_m *MockQuotaManager) Set(ctx context.Context, serviceUserName string, projectName string, serviceName string, _a4 Quota) error {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, _a4)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Set")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, Quota) error); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName, _a4)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockQuotaManager_Set_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Set'
+type MockQuotaManager_Set_Call struct {
+	*mock.Call
+}
+
+// Set is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+//   - _a4 Quota
+func (_e *MockQuotaManager_Expecter) Set(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}, _a4 interface{}) *MockQuotaManager_Set_Call {
+	return &MockQuotaManager_Set_Call{Call: _e.mock.On("Set", ctx, serviceUserName, projectName, serviceName, _a4)}
+}
+
+func (_c *MockQuotaManager_Set_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string, _a4 Quota)) *MockQuotaManager_Set_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(Quota))
+	})
+	return _c
+}
+
+func (_c *MockQuotaManager_Set_Call) Return(_a0 error) *MockQuotaManager_Set_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockQuotaManager_Set_Call) RunAndReturn(run func(context.Context, string, string, string, Quota) error) *MockQuotaManager_Set_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockQuotaManager creates a new instance of MockQuotaManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockQuotaManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockQuotaManager {
+	mock := &MockQuotaManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
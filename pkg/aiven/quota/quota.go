@@ -0,0 +1,94 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aiven/aiven-go-client/v2"
+	"github.com/nais/aivenator/pkg/aiven/rest"
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+// Quota is the set of Kafka client quotas for a service user. Zero values are left unlimited.
+type Quota struct {
+	ConsumerByteRate  float64 `json:"consumer_byte_rate,omitempty"`
+	ProducerByteRate  float64 `json:"producer_byte_rate,omitempty"`
+	RequestPercentage float64 `json:"request_percentage,omitempty"`
+}
+
+func (q Quota) IsZero() bool {
+	return q == Quota{}
+}
+
+func (q Quota) String() string {
+	return fmt.Sprintf("consumer_byte_rate=%g,producer_byte_rate=%g,request_percentage=%g", q.ConsumerByteRate, q.ProducerByteRate, q.RequestPercentage)
+}
+
+type QuotaManager interface {
+	Get(ctx context.Context, serviceUserName, projectName, serviceName string) (*Quota, error)
+	Set(ctx context.Context, serviceUserName, projectName, serviceName string, quota Quota) error
+	Delete(ctx context.Context, serviceUserName, projectName, serviceName string) error
+}
+
+// Manager talks to the Kafka quota endpoints, which are not covered by the Aiven client library
+type Manager struct {
+	client *rest.Client
+}
+
+func NewManager(client *aiven.Client) QuotaManager {
+	return &Manager{
+		client: rest.NewClient(client),
+	}
+}
+
+type quotaRequest struct {
+	User string `json:"user"`
+	Quota
+}
+
+type quotaResponse struct {
+	Quota Quota `json:"quota"`
+}
+
+func (m *Manager) Get(ctx context.Context, serviceUserName, projectName, serviceName string) (*Quota, error) {
+	var response quotaResponse
+	err := metrics.ObserveAivenLatency("Quota_Get", projectName, func() error {
+		body, err := m.client.Do(ctx, http.MethodGet, m.endpoint(projectName, serviceName, "/describe", serviceUserName), nil)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response.Quota, nil
+}
+
+func (m *Manager) Set(ctx context.Context, serviceUserName, projectName, serviceName string, quota Quota) error {
+	return metrics.ObserveAivenLatency("Quota_Set", projectName, func() error {
+		_, err := m.client.Do(ctx, http.MethodPost, m.endpoint(projectName, serviceName, "", ""), quotaRequest{
+			User:  serviceUserName,
+			Quota: quota,
+		})
+		return err
+	})
+}
+
+func (m *Manager) Delete(ctx context.Context, serviceUserName, projectName, serviceName string) error {
+	return metrics.ObserveAivenLatency("Quota_Delete", projectName, func() error {
+		_, err := m.client.Do(ctx, http.MethodDelete, m.endpoint(projectName, serviceName, "", serviceUserName), nil)
+		return err
+	})
+}
+
+func (m *Manager) endpoint(projectName, serviceName, path, serviceUserName string) string {
+	endpoint := fmt.Sprintf("/project/%s/service/%s/quota%s", url.PathEscape(projectName), url.PathEscape(serviceName), path)
+	if serviceUserName != "" {
+		endpoint += "?" + url.Values{"user": {serviceUserName}}.Encode()
+	}
+	return endpoint
+}
@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/aiven/aiven-go-client/v2"
+)
+
+const defaultApiUrl = "https://api.aiven.io/v1"
+
+// Client calls Aiven endpoints that are not covered by the Aiven client library, with its credentials.
+// Errors are returned as aiven.Error, so they can be handled like any other Aiven error.
+type Client struct {
+	client *aiven.Client
+	apiUrl string
+}
+
+func NewClient(client *aiven.Client) *Client {
+	apiUrl := defaultApiUrl
+	if webUrl, ok := os.LookupEnv("AIVEN_WEB_URL"); ok {
+		apiUrl = webUrl + "/v1"
+	}
+	return &Client{
+		client: client,
+		apiUrl: apiUrl,
+	}
+}
+
+// Do sends the body as JSON to the endpoint, relative to the API root, and returns the response body
+func (c *Client) Do(ctx context.Context, method, endpoint string, body any) ([]byte, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiUrl+endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.client.UserAgent)
+	req.Header.Set("Authorization", "aivenv1 "+c.client.APIKey)
+
+	rsp, err := c.client.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	responseBody, err := io.ReadAll(rsp.Body)
+	if err != nil || rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return nil, aiven.Error{Message: string(responseBody), Status: rsp.StatusCode}
+	}
+	return responseBody, nil
+}
@@ -0,0 +1,196 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package schemaregistry
+
+import (
+	context "context"
+
+	aiven "github.com/aiven/aiven-go-client/v2"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockACLManager is an autogenerated mock type for the ACLManager type
+type MockACLManager struct {
+	mock.Mock
+}
+
+type MockACLManager_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockACLManager) EXPECT() *MockACLManager_Expecter {
+	return &MockACLManager_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, projectName, serviceName, acl
+func (_m *MockACLManager) Create(ctx context.Context, projectName string, serviceName string, acl aiven.CreateKafkaSchemaRegistryACLRequest) error {
+	ret := _m.Called(ctx, projectName, serviceName, acl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, aiven.CreateKafkaSchemaRegistryACLRequest) error); ok {
+		r0 = rf(ctx, projectName, serviceName, acl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockACLManager_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockACLManager_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectName string
+//   - serviceName string
+//   - acl aiven.CreateKafkaSchemaRegistryACLRequest
+func (_e *MockACLManager_Expecter) Create(ctx interface{}, projectName interface{}, serviceName interface{}, acl interface{}) *MockACLManager_Create_Call {
+	return &MockACLManager_Create_Call{Call: _e.mock.On("Create", ctx, projectName, serviceName, acl)}
+}
+
+func (_c *MockACLManager_Create_Call) Run(run func(ctx context.Context, projectName string, serviceName string, acl aiven.CreateKafkaSchemaRegistryACLRequest)) *MockACLManager_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(aiven.CreateKafkaSchemaRegistryACLRequest))
+	})
+	return _c
+}
+
+func (_c *MockACLManager_Create_Call) Return(_a0 error) *MockACLManager_Create_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockACLManager_Create_Call) RunAndReturn(run func(context.Context, string, string, aiven.CreateKafkaSchemaRegistryACLRequest) error) *MockACLManager_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: ctx, projectName, serviceName, aclID
+func (_m *MockACLManager) Delete(ctx context.Context, projectName string, serviceName string, aclID string) error {
+	ret := _m.Called(ctx, projectName, serviceName, aclID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, projectName, serviceName, aclID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockACLManager_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type MockACLManager_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectName string
+//   - serviceName string
+//   - aclID string
+func (_e *MockACLManager_Expecter) Delete(ctx interface{}, projectName interface{}, serviceName interface{}, aclID interface{}) *MockACLManager_Delete_Call {
+	return &MockACLManager_Delete_Call{Call: _e.mock.On("Delete", ctx, projectName, serviceName, aclID)}
+}
+
+func (_c *MockACLManager_Delete_Call) Run(run func(ctx context.Context, projectName string, serviceName string, aclID string)) *MockACLManager_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockACLManager_Delete_Call) Return(_a0 error) *MockACLManager_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockACLManager_Delete_Call) RunAndReturn(run func(context.Context, string, string, string) error) *MockACLManager_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx, projectName, serviceName
+func (_m *MockACLManager) List(ctx context.Context, projectName string, serviceName string) ([]*aiven.KafkaSchemaRegistryACL, error) {
+	ret := _m.Called(ctx, projectName, serviceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*aiven.KafkaSchemaRegistryACL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*aiven.KafkaSchemaRegistryACL, error)); ok {
+		return rf(ctx, projectName, serviceName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*aiven.KafkaSchemaRegistryACL); ok {
+		r0 = rf(ctx, projectName, serviceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*aiven.KafkaSchemaRegistryACL)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, projectName, serviceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockACLManager_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type MockACLManager_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - projectName string
+//   - serviceName string
+func (_e *MockACLManager_Expecter) List(ctx interface{}, projectName interface{}, serviceName interface{}) *MockACLManager_List_Call {
+	return &MockACLManager_List_Call{Call: _e.mock.On("List", ctx, projectName, serviceName)}
+}
+
+func (_c *MockACLManager_List_Call) Run(run func(ctx context.Context, projectName string, serviceName string)) *MockACLManager_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockACLManager_List_Call) Return(_a0 []*aiven.KafkaSchemaRegistryACL, _a1 error) *MockACLManager_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockACLManager_List_Call) RunAndReturn(run func(context.Context, string, string) ([]*aiven.KafkaSchemaRegistryACL, error)) *MockACLManager_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockACLManager creates a new instance of MockACLManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockACLManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockACLManager {
+	mock := &MockACLManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
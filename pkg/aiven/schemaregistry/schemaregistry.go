@@ -0,0 +1,52 @@
+package schemaregistry
+
+import (
+	"context"
+
+	"github.com/aiven/aiven-go-client/v2"
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+const (
+	PermissionRead  = "schema_registry_read"
+	PermissionWrite = "schema_registry_write"
+)
+
+type ACLManager interface {
+	List(ctx context.Context, projectName, serviceName string) ([]*aiven.KafkaSchemaRegistryACL, error)
+	Create(ctx context.Context, projectName, serviceName string, acl aiven.CreateKafkaSchemaRegistryACLRequest) error
+	Delete(ctx context.Context, projectName, serviceName, aclID string) error
+}
+
+type Manager struct {
+	acls *aiven.KafkaSchemaRegistryACLHandler
+}
+
+func NewManager(acls *aiven.KafkaSchemaRegistryACLHandler) ACLManager {
+	return &Manager{
+		acls: acls,
+	}
+}
+
+func (m *Manager) List(ctx context.Context, projectName, serviceName string) ([]*aiven.KafkaSchemaRegistryACL, error) {
+	var acls []*aiven.KafkaSchemaRegistryACL
+	err := metrics.ObserveAivenLatency("SchemaRegistryACL_List", projectName, func() error {
+		var err error
+		acls, err = m.acls.List(ctx, projectName, serviceName)
+		return err
+	})
+	return acls, err
+}
+
+func (m *Manager) Create(ctx context.Context, projectName, serviceName string, acl aiven.CreateKafkaSchemaRegistryACLRequest) error {
+	return metrics.ObserveAivenLatency("SchemaRegistryACL_Create", projectName, func() error {
+		_, err := m.acls.Create(ctx, projectName, serviceName, acl)
+		return err
+	})
+}
+
+func (m *Manager) Delete(ctx context.Context, projectName, serviceName, aclID string) error {
+	return metrics.ObserveAivenLatency("SchemaRegistryACL_Delete", projectName, func() error {
+		return m.acls.Delete(ctx, projectName, serviceName, aclID)
+	})
+}
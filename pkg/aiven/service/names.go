@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// Main project services with instances named by applications
+const (
+	Redis      = "redis"
+	OpenSearch = "opensearch"
+	InfluxDB   = "influxdb"
+)
+
+// DefaultNameTemplates are the service names used when no other template is configured
+var DefaultNameTemplates = map[string]string{
+	Redis:      "redis-{{ .Namespace }}-{{ .Instance }}",
+	OpenSearch: "{{ .Instance }}",
+	InfluxDB:   "{{ .Instance }}",
+}
+
+// NameResolver finds the Aiven service behind an instance requested by an application.
+// Resolution errors are unrecoverable, they come from the configuration or the instance name.
+type NameResolver interface {
+	ResolveServiceName(serviceType string, application *aiven_nais_io_v1.AivenApplication, instance string) (string, error)
+}
+
+// NameData is available to name templates
+type NameData struct {
+	Namespace   string
+	Application string
+	Instance    string
+}
+
+// NameConfig decides how instances are mapped to service names
+type NameConfig struct {
+	// Templates are Go templates for the service name of each service type, over NameData
+	Templates map[string]string
+	// Mappings are service names for specific instances, keyed by type/namespace/instance, and take precedence over templates
+	Mappings map[string]string
+}
+
+type TemplateNameResolver struct {
+	templates map[string]*template.Template
+	mappings  map[string]string
+}
+
+// NewTemplateNameResolver parses the templates of the config, falling back to the defaults for service types without one
+func NewTemplateNameResolver(config NameConfig) (*TemplateNameResolver, error) {
+	resolver := &TemplateNameResolver{
+		templates: make(map[string]*template.Template, len(DefaultNameTemplates)),
+		mappings:  config.Mappings,
+	}
+	for serviceType, text := range config.Templates {
+		if _, ok := DefaultNameTemplates[serviceType]; !ok {
+			return nil, fmt.Errorf("unknown service type %q in name templates", serviceType)
+		}
+		if err := resolver.parse(serviceType, text); err != nil {
+			return nil, err
+		}
+	}
+	for serviceType, text := range DefaultNameTemplates {
+		if _, ok := resolver.templates[serviceType]; ok {
+			continue
+		}
+		if err := resolver.parse(serviceType, text); err != nil {
+			return nil, err
+		}
+	}
+	for key := range config.Mappings {
+		serviceType, _, _ := strings.Cut(key, "/")
+		if _, ok := DefaultNameTemplates[serviceType]; !ok || strings.Count(key, "/") != 2 {
+			return nil, fmt.Errorf("invalid name mapping %q, must be type/namespace/instance", key)
+		}
+	}
+	return resolver, nil
+}
+
+// NewDefaultNameResolver resolves service names with the default templates, which are known to parse
+func NewDefaultNameResolver() *TemplateNameResolver {
+	resolver, err := NewTemplateNameResolver(NameConfig{})
+	if err != nil {
+		panic(err)
+	}
+	return resolver
+}
+
+var defaultNameResolver = NewDefaultNameResolver()
+
+// DefaultServiceName is the name the default template gives the service of an instance, whatever the configured
+// templates and mappings. Handlers use it to tell services dedicated to a namespace from services configured otherwise.
+func DefaultServiceName(serviceType string, application *aiven_nais_io_v1.AivenApplication, instance string) (string, error) {
+	return defaultNameResolver.ResolveServiceName(serviceType, application, instance)
+}
+
+func (r *TemplateNameResolver) parse(serviceType, text string) error {
+	tmpl, err := template.New(serviceType).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid name template for %s: %w", serviceType, err)
+	}
+	var name bytes.Buffer
+	err = tmpl.Execute(&name, NameData{Namespace: "namespace", Application: "application", Instance: "instance"})
+	if err != nil {
+		return fmt.Errorf("invalid name template for %s: %w", serviceType, err)
+	}
+	r.templates[serviceType] = tmpl
+	return nil
+}
+
+func (r *TemplateNameResolver) ResolveServiceName(serviceType string, application *aiven_nais_io_v1.AivenApplication, instance string) (string, error) {
+	if name, ok := r.mappings[fmt.Sprintf("%s/%s/%s", serviceType, application.GetNamespace(), instance)]; ok {
+		return name, nil
+	}
+	tmpl, ok := r.templates[serviceType]
+	if !ok {
+		return "", fmt.Errorf("no name template for service type %s: %w", serviceType, utils.UnrecoverableError)
+	}
+
+	var name bytes.Buffer
+	err := tmpl.Execute(&name, NameData{
+		Namespace:   application.GetNamespace(),
+		Application: application.GetName(),
+		Instance:    instance,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s service name for instance %s: %v: %w", serviceType, instance, err, utils.UnrecoverableError)
+	}
+	if name.Len() == 0 {
+		return "", fmt.Errorf("%s service name for instance %s is empty: %w", serviceType, instance, utils.UnrecoverableError)
+	}
+	return name.String(), nil
+}
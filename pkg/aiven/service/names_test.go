@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+func TestDefaultNameResolver(t *testing.T) {
+	application := aiven_nais_io_v1.NewAivenApplicationBuilder("my-app", "team-a").Build()
+	resolver := NewDefaultNameResolver()
+
+	name, err := resolver.ResolveServiceName(Redis, &application, "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "redis-team-a-cache", name)
+
+	name, err = resolver.ResolveServiceName(OpenSearch, &application, "opensearch-team-a-logs")
+	assert.NoError(t, err)
+	assert.Equal(t, "opensearch-team-a-logs", name)
+}
+
+func TestDefaultServiceName(t *testing.T) {
+	application := aiven_nais_io_v1.NewAivenApplicationBuilder("my-app", "team-a").Build()
+	name, err := DefaultServiceName(Redis, &application, "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "redis-team-a-cache", name)
+}
+
+func TestTemplateNameResolver(t *testing.T) {
+	application := aiven_nais_io_v1.NewAivenApplicationBuilder("my-app", "team-a").Build()
+	resolver, err := NewTemplateNameResolver(NameConfig{
+		Templates: map[string]string{
+			Redis:    "redis-tenant-{{ .Instance }}",
+			InfluxDB: "{{ .Instance }}",
+		},
+		Mappings: map[string]string{
+			"redis/team-a/sessions": "redis-shared-sessions",
+		},
+	})
+	assert.NoError(t, err)
+
+	name, err := resolver.ResolveServiceName(Redis, &application, "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "redis-tenant-cache", name)
+
+	name, err = resolver.ResolveServiceName(Redis, &application, "sessions")
+	assert.NoError(t, err)
+	assert.Equal(t, "redis-shared-sessions", name)
+
+	name, err = resolver.ResolveServiceName(OpenSearch, &application, "opensearch-team-a-logs")
+	assert.NoError(t, err)
+	assert.Equal(t, "opensearch-team-a-logs", name)
+
+	_, err = resolver.ResolveServiceName("postgres", &application, "db")
+	assert.ErrorIs(t, err, utils.UnrecoverableError)
+}
+
+func TestTemplateNameResolverInvalidConfig(t *testing.T) {
+	for _, config := range []NameConfig{
+		{Templates: map[string]string{"postgres": "{{ .Instance }}"}},
+		{Templates: map[string]string{Redis: "{{ .Instance"}},
+		{Templates: map[string]string{Redis: "{{ .Team }}"}},
+		{Mappings: map[string]string{"redis/cache": "redis-shared"}},
+		{Mappings: map[string]string{"postgres/team-a/db": "postgres-shared"}},
+	} {
+		_, err := NewTemplateNameResolver(config)
+		assert.Error(t, err, "%+v", config)
+	}
+}
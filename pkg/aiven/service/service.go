@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/metrics"
@@ -15,25 +18,92 @@ type ServiceManager interface {
 
 type Manager struct {
 	service      *aiven.ServicesHandler
+	routes       AddressRoutes
 	addressCache map[cacheKey]*ServiceAddresses
 }
 
+// AddressRoutes decides which address to use when a service component is available on several routes,
+// such as dynamic, private, privatelink or public
+type AddressRoutes struct {
+	// Default is the list of preferred routes for all projects, most preferred first
+	Default []string
+	// Projects holds preferred routes for specific projects, used instead of Default
+	Projects map[string][]string
+}
+
+func (a AddressRoutes) forProject(projectName string) []string {
+	if routes, ok := a.Projects[projectName]; ok {
+		return routes
+	}
+	return a.Default
+}
+
 type cacheKey struct {
 	projectName string
 	serviceName string
 }
 
+// Components with client addresses, used as keys in ServiceAddresses.Routes
+const (
+	ComponentKafka                = "kafka"
+	ComponentKafkaSASL            = "kafka_sasl"
+	ComponentSchemaRegistry       = "schema_registry"
+	ComponentKafkaREST            = "kafka_rest"
+	ComponentKafkaConnect         = "kafka_connect"
+	ComponentOpenSearch           = "opensearch"
+	ComponentOpenSearchDashboards = "opensearch_dashboards"
+	ComponentRedis                = "redis"
+	ComponentValkey               = "valkey"
+	ComponentDragonfly            = "dragonfly"
+	ComponentInfluxDB             = "influxdb"
+)
+
 type ServiceAddresses struct {
 	ServiceURI     string
+	KafkaSASL      string
 	SchemaRegistry string
+	KafkaREST      string
+	KafkaConnect   string
 	OpenSearch     string
-	Redis          string
-	InfluxDB       string
+	// OpenSearchDashboards is empty when the service has no dashboards
+	OpenSearchDashboards string
+	Redis                string
+	Valkey               string
+	Dragonfly            string
+	InfluxDB             string
+	// Routes holds the route of each address, keyed by component, when preferred routes are configured.
+	// Each address is on the most preferred route its component is available on.
+	Routes map[string]string
 }
 
-func NewManager(service *aiven.ServicesHandler) ServiceManager {
+// Route describes the routes of the addresses of the given components, for recording on secrets.
+// It is the route when all of them share one, otherwise a list of component=route, and empty without preferred routes.
+func (a *ServiceAddresses) Route(components ...string) string {
+	pairs := make([]string, 0, len(components))
+	shared := ""
+	for _, component := range components {
+		route, ok := a.Routes[component]
+		if !ok || route == "" {
+			continue
+		}
+		if len(pairs) == 0 {
+			shared = route
+		} else if route != shared {
+			shared = ""
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", component, route))
+	}
+	if len(pairs) <= 1 || shared != "" {
+		return shared
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func NewManager(service *aiven.ServicesHandler, routes AddressRoutes) ServiceManager {
 	return &Manager{
 		service:      service,
+		routes:       routes,
 		addressCache: map[cacheKey]*ServiceAddresses{},
 	}
 }
@@ -50,13 +120,7 @@ func (r *Manager) GetServiceAddresses(ctx context.Context, projectName, serviceN
 		if err != nil {
 			return nil, err
 		}
-		addresses = &ServiceAddresses{
-			ServiceURI:     getServiceURI(aivenService),
-			SchemaRegistry: getServiceAddress(aivenService, "schema_registry", "https"),
-			OpenSearch:     getServiceAddress(aivenService, "opensearch", "https"),
-			Redis:          getServiceAddress(aivenService, "redis", "rediss"),
-			InfluxDB:       getServiceAddress(aivenService, "influxdb", "https+influxdb"),
-		}
+		addresses = resolveAddresses(aivenService, r.routes.forProject(projectName))
 		r.addressCache[key] = addresses
 	}
 	return addresses, nil
@@ -72,23 +136,88 @@ func (r *Manager) Get(ctx context.Context, projectName, serviceName string) (*ai
 	return service, err
 }
 
-func getServiceURI(service *aiven.Service) string {
-	return service.URI
+// addressResolver finds the address of each component on the most preferred route it is available on
+type addressResolver struct {
+	service   *aiven.Service
+	preferred []string
+	routes    map[string]string
 }
 
-func getServiceAddress(service *aiven.Service, componentName, scheme string) string {
-	component := findComponent(componentName, service.Components)
+func resolveAddresses(service *aiven.Service, preferred []string) *ServiceAddresses {
+	r := addressResolver{
+		service:   service,
+		preferred: preferred,
+	}
+	if len(preferred) > 0 {
+		r.routes = make(map[string]string)
+	}
+	return &ServiceAddresses{
+		ServiceURI:           r.serviceURI(),
+		KafkaSASL:            r.kafkaAddress(ComponentKafkaSASL, "sasl"),
+		SchemaRegistry:       r.address(ComponentSchemaRegistry, "https"),
+		KafkaREST:            r.address(ComponentKafkaREST, "https"),
+		KafkaConnect:         r.address(ComponentKafkaConnect, "https"),
+		OpenSearch:           r.address(ComponentOpenSearch, "https"),
+		OpenSearchDashboards: r.address(ComponentOpenSearchDashboards, "https"),
+		Redis:                r.address(ComponentRedis, "rediss"),
+		Valkey:               r.address(ComponentValkey, "rediss"),
+		Dragonfly:            r.address(ComponentDragonfly, "rediss"),
+		InfluxDB:             r.address(ComponentInfluxDB, "https+influxdb"),
+		Routes:               r.routes,
+	}
+}
+
+// serviceURI uses the certificate listener on a preferred route, or the service URI when there is none
+func (r addressResolver) serviceURI() string {
+	component := r.findComponent(ComponentKafka, func(c *aiven.ServiceComponents) bool {
+		return c.Component == "kafka" && c.KafkaAuthenticationMethod == "certificate"
+	})
+	if component == nil || !slices.Contains(r.preferred, component.Route) {
+		delete(r.routes, ComponentKafka)
+		return r.service.URI
+	}
+	return fmt.Sprintf("%s:%d", component.Host, component.Port)
+}
+
+func (r addressResolver) kafkaAddress(key, authenticationMethod string) string {
+	component := r.findComponent(key, func(c *aiven.ServiceComponents) bool {
+		return c.Component == "kafka" && c.KafkaAuthenticationMethod == authenticationMethod
+	})
+	if component != nil {
+		return fmt.Sprintf("%s:%d", component.Host, component.Port)
+	}
+	return ""
+}
+
+func (r addressResolver) address(componentName, scheme string) string {
+	component := r.findComponent(componentName, func(c *aiven.ServiceComponents) bool {
+		return c.Component == componentName
+	})
 	if component != nil {
 		return fmt.Sprintf("%s://%s:%d", scheme, component.Host, component.Port)
 	}
 	return ""
 }
 
-func findComponent(needle string, haystack []*aiven.ServiceComponents) *aiven.ServiceComponents {
-	for _, c := range haystack {
-		if c.Component == needle {
-			return c
+// findComponent returns the first matching component on the most preferred route, or the first matching component on
+// any route, and records the route of the component under key
+func (r addressResolver) findComponent(key string, matches func(c *aiven.ServiceComponents) bool) *aiven.ServiceComponents {
+	var found *aiven.ServiceComponents
+	rank := len(r.preferred)
+	for _, c := range r.service.Components {
+		if !matches(c) {
+			continue
+		}
+		if found == nil {
+			found = c
 		}
+		if i := slices.Index(r.preferred, c.Route); i >= 0 && i < rank {
+			found = c
+			rank = i
+		}
+	}
+	if found != nil && r.routes != nil {
+		r.routes[key] = found.Route
 	}
-	return nil
+	return found
 }
@@ -1,4 +1,4 @@
-// Code generated by mockery v2.36.0. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package serviceuser
 
@@ -31,6 +31,10 @@ func (_m *MockServiceUserManager) EXPECT() *MockServiceUserManager_Expecter {
 func (_m *MockServiceUserManager) Create(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl, logger logrus.FieldLogger) (*aiven.ServiceUser, error) {
 	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, accessControl, logger)
 
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
 	var r0 *aiven.ServiceUser
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl, logrus.FieldLogger) (*aiven.ServiceUser, error)); ok {
@@ -90,6 +94,10 @@ func (_c *MockServiceUserManager_Create_Call) RunAndReturn(run func(context.Cont
 func (_m *MockServiceUserManager) Delete(ctx context.Context, serviceUserName string, projectName string, serviceName string, logger logrus.FieldLogger) error {
 	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, logger)
 
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, logrus.FieldLogger) error); ok {
 		r0 = rf(ctx, serviceUserName, projectName, serviceName, logger)
@@ -136,6 +144,10 @@ func (_c *MockServiceUserManager_Delete_Call) RunAndReturn(run func(context.Cont
 func (_m *MockServiceUserManager) Get(ctx context.Context, serviceUserName string, projectName string, serviceName string, logger logrus.FieldLogger) (*aiven.ServiceUser, error) {
 	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, logger)
 
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
 	var r0 *aiven.ServiceUser
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, logrus.FieldLogger) (*aiven.ServiceUser, error)); ok {
@@ -190,10 +202,14 @@ func (_c *MockServiceUserManager_Get_Call) RunAndReturn(run func(context.Context
 	return _c
 }
 
-// GetCacheExpiration provides a mock function with given fields:
+// GetCacheExpiration provides a mock function with no fields
 func (_m *MockServiceUserManager) GetCacheExpiration() time.Duration {
 	ret := _m.Called()
 
+	if len(ret) == 0 {
+		panic("no return value specified for GetCacheExpiration")
+	}
+
 	var r0 time.Duration
 	if rf, ok := ret.Get(0).(func() time.Duration); ok {
 		r0 = rf()
@@ -263,6 +279,69 @@ func (_c *MockServiceUserManager_ObserveServiceUsersCount_Call) Return() *MockSe
 }
 
 func (_c *MockServiceUserManager_ObserveServiceUsersCount_Call) RunAndReturn(run func(context.Context, string, string, logrus.FieldLogger)) *MockServiceUserManager_ObserveServiceUsersCount_Call {
+	_c.Run(run)
+	return _c
+}
+
+// UpdateAccessControl provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName, accessControl, logger
+func (_m *MockServiceUserManager) UpdateAccessControl(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl, logger logrus.FieldLogger) (*aiven.ServiceUser, error) {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, accessControl, logger)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAccessControl")
+	}
+
+	var r0 *aiven.ServiceUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl, logrus.FieldLogger) (*aiven.ServiceUser, error)); ok {
+		return rf(ctx, serviceUserName, projectName, serviceName, accessControl, logger)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl, logrus.FieldLogger) *aiven.ServiceUser); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName, accessControl, logger)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*aiven.ServiceUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, *aiven.AccessControl, logrus.FieldLogger) error); ok {
+		r1 = rf(ctx, serviceUserName, projectName, serviceName, accessControl, logger)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceUserManager_UpdateAccessControl_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAccessControl'
+type MockServiceUserManager_UpdateAccessControl_Call struct {
+	*mock.Call
+}
+
+// UpdateAccessControl is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+//   - accessControl *aiven.AccessControl
+//   - logger logrus.FieldLogger
+func (_e *MockServiceUserManager_Expecter) UpdateAccessControl(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}, accessControl interface{}, logger interface{}) *MockServiceUserManager_UpdateAccessControl_Call {
+	return &MockServiceUserManager_UpdateAccessControl_Call{Call: _e.mock.On("UpdateAccessControl", ctx, serviceUserName, projectName, serviceName, accessControl, logger)}
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl, logger logrus.FieldLogger)) *MockServiceUserManager_UpdateAccessControl_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(*aiven.AccessControl), args[5].(logrus.FieldLogger))
+	})
+	return _c
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) Return(_a0 *aiven.ServiceUser, _a1 error) *MockServiceUserManager_UpdateAccessControl_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) RunAndReturn(run func(context.Context, string, string, string, *aiven.AccessControl, logrus.FieldLogger) (*aiven.ServiceUser, error)) *MockServiceUserManager_UpdateAccessControl_Call {
 	_c.Call.Return(run)
 	return _c
 }
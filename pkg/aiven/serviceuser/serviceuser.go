@@ -12,14 +12,40 @@ import (
 	"time"
 )
 
+// Naming conventions of service users, the underscore convention is the current one
 const (
-	dotSeparator        = "dot"
-	underscoreSeparator = "underscore"
-	other               = "other"
+	ConventionDot        = "dot"
+	ConventionUnderscore = "underscore"
+	ConventionOther      = "other"
 
 	cacheExpiration = 5 * time.Minute
 )
 
+// Convention classifies a service user name. Current names are team_app_hash_suffix, where the hash is 8 hex characters
+// and the suffix may itself contain underscores.
+func Convention(serviceUserName string) string {
+	parts := strings.SplitN(serviceUserName, "_", 4)
+	if len(parts) == 4 && isHash(parts[2]) {
+		return ConventionUnderscore
+	}
+	if strings.Contains(serviceUserName, ".") {
+		return ConventionDot
+	}
+	return ConventionOther
+}
+
+func isHash(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
 type cacheKey struct {
 	projectName     string
 	serviceName     string
@@ -37,6 +63,7 @@ type ServiceUserManager interface {
 	Create(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl, logger log.FieldLogger) (*aiven.ServiceUser, error)
 	Get(ctx context.Context, serviceUserName, projectName, serviceName string, logger log.FieldLogger) (*aiven.ServiceUser, error)
 	Delete(ctx context.Context, serviceUserName, projectName, serviceName string, logger log.FieldLogger) error
+	UpdateAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl, logger log.FieldLogger) (*aiven.ServiceUser, error)
 	ObserveServiceUsersCount(ctx context.Context, projectName, serviceName string, logger log.FieldLogger)
 	GetCacheExpiration() time.Duration
 }
@@ -67,20 +94,21 @@ func (m *Manager) ObserveServiceUsersCount(ctx context.Context, projectName, ser
 		logger.Errorf("not able to fetch service users users: %s", err)
 	} else {
 		counts := m.countUsersAndUpdateCache(projectName, serviceName, users)
-		metrics.ServiceUsersCount.WithLabelValues(projectName, dotSeparator).Set(float64(counts.dot))
-		metrics.ServiceUsersCount.WithLabelValues(projectName, underscoreSeparator).Set(float64(counts.underscore))
-		metrics.ServiceUsersCount.WithLabelValues(projectName, other).Set(float64(counts.other))
+		metrics.ServiceUsersCount.WithLabelValues(projectName, ConventionDot).Set(float64(counts.dot))
+		metrics.ServiceUsersCount.WithLabelValues(projectName, ConventionUnderscore).Set(float64(counts.underscore))
+		metrics.ServiceUsersCount.WithLabelValues(projectName, ConventionOther).Set(float64(counts.other))
 	}
 }
 
 func (m *Manager) countUsersAndUpdateCache(projectName, serviceName string, users []*aiven.ServiceUser) userCount {
 	counts := userCount{}
 	for _, user := range users {
-		if strings.Count(user.Username, "_") == 3 {
+		switch Convention(user.Username) {
+		case ConventionUnderscore:
 			counts.underscore++
-		} else if strings.Count(user.Username, ".") >= 1 {
+		case ConventionDot:
 			counts.dot++
-		} else {
+		default:
 			counts.other++
 		}
 		m.serviceUserCache.Set(cacheKey{projectName, serviceName, user.Username}, user, cache.WithExpiration(m.GetCacheExpiration()))
@@ -159,3 +187,25 @@ func (m *Manager) Create(ctx context.Context, serviceUserName, projectName, serv
 	m.ObserveServiceUsersCount(ctx, projectName, serviceName, logger)
 	return aivenUser, nil
 }
+
+// UpdateAccessControl replaces the access control of an existing service user, keeping its credentials
+func (m *Manager) UpdateAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	operation := aiven.UpdateOperationSetAccessControl
+	req := aiven.ModifyServiceUserRequest{
+		Operation:     &operation,
+		AccessControl: accessControl,
+	}
+
+	var aivenUser *aiven.ServiceUser
+	err := metrics.ObserveAivenLatency("ServiceUser_Update", projectName, func() error {
+		var err error
+		aivenUser, err = m.serviceUsers.Update(ctx, projectName, serviceName, serviceUserName, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.serviceUserCache.Set(cacheKey{projectName, serviceName, serviceUserName}, aivenUser, cache.WithExpiration(cacheExpiration))
+	logger.Infof("Updated access control of service user %s", serviceUserName)
+	return aivenUser, nil
+}
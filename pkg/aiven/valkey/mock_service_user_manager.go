@@ -0,0 +1,211 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package valkey
+
+import (
+	context "context"
+
+	aiven "github.com/aiven/aiven-go-client/v2"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockServiceUserManager is an autogenerated mock type for the ServiceUserManager type
+type MockServiceUserManager struct {
+	mock.Mock
+}
+
+type MockServiceUserManager_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockServiceUserManager) EXPECT() *MockServiceUserManager_Expecter {
+	return &MockServiceUserManager_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName, accessControl
+func (_m *MockServiceUserManager) Create(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl) (*aiven.ServiceUser, error) {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, accessControl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *aiven.ServiceUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl) (*aiven.ServiceUser, error)); ok {
+		return rf(ctx, serviceUserName, projectName, serviceName, accessControl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl) *aiven.ServiceUser); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName, accessControl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*aiven.ServiceUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, *aiven.AccessControl) error); ok {
+		r1 = rf(ctx, serviceUserName, projectName, serviceName, accessControl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceUserManager_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockServiceUserManager_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+//   - accessControl *aiven.AccessControl
+func (_e *MockServiceUserManager_Expecter) Create(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}, accessControl interface{}) *MockServiceUserManager_Create_Call {
+	return &MockServiceUserManager_Create_Call{Call: _e.mock.On("Create", ctx, serviceUserName, projectName, serviceName, accessControl)}
+}
+
+func (_c *MockServiceUserManager_Create_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl)) *MockServiceUserManager_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(*aiven.AccessControl))
+	})
+	return _c
+}
+
+func (_c *MockServiceUserManager_Create_Call) Return(_a0 *aiven.ServiceUser, _a1 error) *MockServiceUserManager_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceUserManager_Create_Call) RunAndReturn(run func(context.Context, string, string, string, *aiven.AccessControl) (*aiven.ServiceUser, error)) *MockServiceUserManager_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccessControl provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName
+func (_m *MockServiceUserManager) GetAccessControl(ctx context.Context, serviceUserName string, projectName string, serviceName string) (*aiven.AccessControl, error) {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccessControl")
+	}
+
+	var r0 *aiven.AccessControl
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*aiven.AccessControl, error)); ok {
+		return rf(ctx, serviceUserName, projectName, serviceName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *aiven.AccessControl); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*aiven.AccessControl)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, serviceUserName, projectName, serviceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceUserManager_GetAccessControl_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccessControl'
+type MockServiceUserManager_GetAccessControl_Call struct {
+	*mock.Call
+}
+
+// GetAccessControl is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+func (_e *MockServiceUserManager_Expecter) GetAccessControl(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}) *MockServiceUserManager_GetAccessControl_Call {
+	return &MockServiceUserManager_GetAccessControl_Call{Call: _e.mock.On("GetAccessControl", ctx, serviceUserName, projectName, serviceName)}
+}
+
+func (_c *MockServiceUserManager_GetAccessControl_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string)) *MockServiceUserManager_GetAccessControl_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockServiceUserManager_GetAccessControl_Call) Return(_a0 *aiven.AccessControl, _a1 error) *MockServiceUserManager_GetAccessControl_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceUserManager_GetAccessControl_Call) RunAndReturn(run func(context.Context, string, string, string) (*aiven.AccessControl, error)) *MockServiceUserManager_GetAccessControl_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateAccessControl provides a mock function with given fields: ctx, serviceUserName, projectName, serviceName, accessControl
+func (_m *MockServiceUserManager) UpdateAccessControl(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl) error {
+	ret := _m.Called(ctx, serviceUserName, projectName, serviceName, accessControl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateAccessControl")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *aiven.AccessControl) error); ok {
+		r0 = rf(ctx, serviceUserName, projectName, serviceName, accessControl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockServiceUserManager_UpdateAccessControl_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateAccessControl'
+type MockServiceUserManager_UpdateAccessControl_Call struct {
+	*mock.Call
+}
+
+// UpdateAccessControl is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serviceUserName string
+//   - projectName string
+//   - serviceName string
+//   - accessControl *aiven.AccessControl
+func (_e *MockServiceUserManager_Expecter) UpdateAccessControl(ctx interface{}, serviceUserName interface{}, projectName interface{}, serviceName interface{}, accessControl interface{}) *MockServiceUserManager_UpdateAccessControl_Call {
+	return &MockServiceUserManager_UpdateAccessControl_Call{Call: _e.mock.On("UpdateAccessControl", ctx, serviceUserName, projectName, serviceName, accessControl)}
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) Run(run func(ctx context.Context, serviceUserName string, projectName string, serviceName string, accessControl *aiven.AccessControl)) *MockServiceUserManager_UpdateAccessControl_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(*aiven.AccessControl))
+	})
+	return _c
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) Return(_a0 error) *MockServiceUserManager_UpdateAccessControl_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockServiceUserManager_UpdateAccessControl_Call) RunAndReturn(run func(context.Context, string, string, string, *aiven.AccessControl) error) *MockServiceUserManager_UpdateAccessControl_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockServiceUserManager creates a new instance of MockServiceUserManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockServiceUserManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockServiceUserManager {
+	mock := &MockServiceUserManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,133 @@
+package valkey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aiven/aiven-go-client/v2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nais/aivenator/pkg/aiven/rest"
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+// accessControl holds the valkey_acl_ fields of a service user, which the Aiven client library does not have
+type accessControl struct {
+	Categories []string `json:"valkey_acl_categories"`
+	Commands   []string `json:"valkey_acl_commands"`
+	Keys       []string `json:"valkey_acl_keys"`
+	Channels   []string `json:"valkey_acl_channels"`
+}
+
+// fromAiven takes the Redis fields of the access control, which mean the same for Valkey
+func fromAiven(ac *aiven.AccessControl) accessControl {
+	return accessControl{
+		Categories: ac.RedisACLCategories,
+		Commands:   ac.RedisACLCommands,
+		Keys:       ac.RedisACLKeys,
+		Channels:   ac.RedisACLChannels,
+	}
+}
+
+func (ac accessControl) toAiven() aiven.AccessControl {
+	return aiven.AccessControl{
+		RedisACLCategories: ac.Categories,
+		RedisACLCommands:   ac.Commands,
+		RedisACLKeys:       ac.Keys,
+		RedisACLChannels:   ac.Channels,
+	}
+}
+
+// ServiceUserManager sets the access control of Valkey service users. Access control is passed in the Redis fields of
+// aiven.AccessControl, so the Redis handler treats both service types alike.
+type ServiceUserManager interface {
+	Create(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl) (*aiven.ServiceUser, error)
+	GetAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string) (*aiven.AccessControl, error)
+	UpdateAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl) error
+}
+
+// Manager talks to the service user endpoints directly, since the Aiven client library has no Valkey access control
+type Manager struct {
+	client *rest.Client
+}
+
+func NewManager(client *aiven.Client) ServiceUserManager {
+	return &Manager{
+		client: rest.NewClient(client),
+	}
+}
+
+type createRequest struct {
+	Username      string        `json:"username"`
+	AccessControl accessControl `json:"access_control"`
+}
+
+type updateRequest struct {
+	Operation     string        `json:"operation"`
+	AccessControl accessControl `json:"access_control"`
+}
+
+type userResponse struct {
+	User struct {
+		aiven.ServiceUser
+		AccessControl accessControl `json:"access_control"`
+	} `json:"user"`
+}
+
+func (m *Manager) Create(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl) (*aiven.ServiceUser, error) {
+	var response userResponse
+	err := metrics.ObserveAivenLatency("Valkey_ServiceUser_Create", projectName, func() error {
+		body, err := m.client.Do(ctx, http.MethodPost, m.endpoint(projectName, serviceName, ""), createRequest{
+			Username:      serviceUserName,
+			AccessControl: fromAiven(accessControl),
+		})
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.ServiceUsersCreated.With(prometheus.Labels{metrics.LabelPool: projectName}).Inc()
+	user := response.User.ServiceUser
+	user.AccessControl = response.User.AccessControl.toAiven()
+	return &user, nil
+}
+
+func (m *Manager) GetAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string) (*aiven.AccessControl, error) {
+	var response userResponse
+	err := metrics.ObserveAivenLatency("Valkey_ServiceUser_Get", projectName, func() error {
+		body, err := m.client.Do(ctx, http.MethodGet, m.endpoint(projectName, serviceName, serviceUserName), nil)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	accessControl := response.User.AccessControl.toAiven()
+	return &accessControl, nil
+}
+
+func (m *Manager) UpdateAccessControl(ctx context.Context, serviceUserName, projectName, serviceName string, accessControl *aiven.AccessControl) error {
+	return metrics.ObserveAivenLatency("Valkey_ServiceUser_Update", projectName, func() error {
+		_, err := m.client.Do(ctx, http.MethodPut, m.endpoint(projectName, serviceName, serviceUserName), updateRequest{
+			Operation:     string(aiven.UpdateOperationSetAccessControl),
+			AccessControl: fromAiven(accessControl),
+		})
+		return err
+	})
+}
+
+func (m *Manager) endpoint(projectName, serviceName, serviceUserName string) string {
+	endpoint := fmt.Sprintf("/project/%s/service/%s/user", url.PathEscape(projectName), url.PathEscape(serviceName))
+	if serviceUserName != "" {
+		endpoint += "/" + url.PathEscape(serviceUserName)
+	}
+	return endpoint
+}
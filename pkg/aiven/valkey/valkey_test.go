@@ -0,0 +1,54 @@
+package valkey
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aiven/aiven-go-client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessControlFields(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"user": map[string]any{
+				"username": "app-r",
+				"password": "secret",
+				"access_control": map[string]any{
+					"valkey_acl_categories": []string{"+@read"},
+					"valkey_acl_keys":       []string{"*"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+	t.Setenv("AIVEN_WEB_URL", server.URL)
+	manager := NewManager(&aiven.Client{Client: server.Client(), APIKey: "token", UserAgent: "aivenator"})
+	ctx := context.Background()
+	desired := &aiven.AccessControl{RedisACLCategories: []string{"+@read"}, RedisACLKeys: []string{"*"}}
+
+	user, err := manager.Create(ctx, "app-r", "my-project", "my-valkey", desired)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", user.Password)
+	assert.Equal(t, []string{"*"}, user.AccessControl.RedisACLKeys)
+
+	existing, err := manager.GetAccessControl(ctx, "app-r", "my-project", "my-valkey")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"+@read"}, existing.RedisACLCategories)
+
+	require.NoError(t, manager.UpdateAccessControl(ctx, "app-r", "my-project", "my-valkey", desired))
+
+	assert.Equal(t, []string{
+		`POST /v1/project/my-project/service/my-valkey/user {"username":"app-r","access_control":{"valkey_acl_categories":["+@read"],"valkey_acl_commands":null,"valkey_acl_keys":["*"],"valkey_acl_channels":null}}`,
+		`GET /v1/project/my-project/service/my-valkey/user/app-r `,
+		`PUT /v1/project/my-project/service/my-valkey/user/app-r {"operation":"set-access-control","access_control":{"valkey_acl_categories":["+@read"],"valkey_acl_commands":null,"valkey_acl_keys":["*"],"valkey_acl_channels":null}}`,
+	}, requests)
+}
@@ -1,6 +1,8 @@
 package annotations
 
 import (
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/nais/aivenator/constants"
 )
 
@@ -18,3 +20,18 @@ func HasTimeLimited(annotations map[string]string) bool {
 	value, found := hasAnnotation(annotations, constants.AivenatorProtectedWithTimeLimitAnnotation)
 	return found && value == "true"
 }
+
+// SetAddressRoute records the routes of the addresses in the secret, as described by ServiceAddresses.Route, removing
+// the annotation when no route was recorded
+func SetAddressRoute(secret *v1.Secret, key, route string) {
+	if route == "" {
+		delete(secret.GetAnnotations(), key)
+		return
+	}
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[key] = route
+	secret.SetAnnotations(annotations)
+}
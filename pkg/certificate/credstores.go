@@ -1,16 +1,70 @@
 package certificate
 
+import (
+	"fmt"
+	"strings"
+)
+
 func GetSecret() string {
 	// This is typically the default password use "everywhere" for these kinds of stores
 	return "changeme"
 }
 
+type StoreFormat string
+
+const (
+	PKCS12 StoreFormat = "PKCS12"
+	JKS    StoreFormat = "JKS"
+)
+
+func ParseStoreFormat(value string) (StoreFormat, error) {
+	switch format := StoreFormat(strings.ToUpper(value)); format {
+	case PKCS12, JKS:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported store format '%s'", value)
+}
+
+// Formats selects what MakeCredStores produces
+type Formats struct {
+	Keystore   StoreFormat
+	Truststore StoreFormat
+	// PEM adds PEM bundles of the client certificate with its key, and with its CA chain
+	PEM bool
+}
+
+// DefaultFormats are the formats produced before formats were selectable
+func DefaultFormats() Formats {
+	return Formats{
+		Keystore:   PKCS12,
+		Truststore: PKCS12,
+	}
+}
+
 type CredStoreData struct {
 	Keystore   []byte
 	Truststore []byte
 	Secret     string
+	// KeyBundle is the client certificate followed by its private key, only set when PEM bundles are requested
+	KeyBundle []byte
+	// CertificateChain is the client certificate followed by the CA, only set when PEM bundles are requested
+	CertificateChain []byte
 }
 
 type Generator interface {
-	MakeCredStores(accessKey, accessCert, caCert string) (*CredStoreData, error)
+	MakeCredStores(accessKey, accessCert, caCert string, formats Formats) (*CredStoreData, error)
+}
+
+func addPEMBundles(data *CredStoreData, accessKey, accessCert, caCert string) {
+	data.KeyBundle = []byte(joinPEM(accessCert, accessKey))
+	data.CertificateChain = []byte(joinPEM(accessCert, caCert))
+}
+
+func joinPEM(blocks ...string) string {
+	builder := strings.Builder{}
+	for _, block := range blocks {
+		builder.WriteString(strings.TrimSpace(block))
+		builder.WriteString("\n")
+	}
+	return builder.String()
 }
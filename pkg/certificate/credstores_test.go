@@ -50,7 +50,7 @@ func TestCredStoreGenerator(t *testing.T) {
 
 	workdir, err := os.MkdirTemp("", "credstores_test-workdir-*")
 	runGenerator := func(t *testing.T, desc string, generator Generator) {
-		stores, err := generator.MakeCredStores(test_user.AccessKey, test_user.AccessCert, caCert)
+		stores, err := generator.MakeCredStores(test_user.AccessKey, test_user.AccessCert, caCert, DefaultFormats())
 		if err != nil {
 			log.Errorf("failed to create cred stores: %v", err)
 			t.Fatal(err)
@@ -20,7 +20,11 @@ func NewExecGenerator() ExecGenerator {
 	return e
 }
 
-func (e ExecGenerator) MakeCredStores(accessKey, accessCert, caCert string) (*CredStoreData, error) {
+func (e ExecGenerator) MakeCredStores(accessKey, accessCert, caCert string, formats Formats) (*CredStoreData, error) {
+	if formats.Keystore != PKCS12 {
+		return nil, fmt.Errorf("unsupported keystore format '%s'", formats.Keystore)
+	}
+
 	workdir, err := ioutil.TempDir("", "exec-store-workdir-*")
 	defer os.RemoveAll(workdir)
 	if err != nil {
@@ -30,15 +34,19 @@ func (e ExecGenerator) MakeCredStores(accessKey, accessCert, caCert string) (*Cr
 	if err != nil {
 		return nil, err
 	}
-	truststore, err := e.MakeTruststore(workdir, caCert)
+	truststore, err := e.MakeTruststore(workdir, caCert, formats.Truststore)
 	if err != nil {
 		return nil, err
 	}
-	return &CredStoreData{
+	data := &CredStoreData{
 		Keystore:   keystore,
 		Truststore: truststore,
 		Secret:     e.secret,
-	}, nil
+	}
+	if formats.PEM {
+		addPEMBundles(data, accessKey, accessCert, caCert)
+	}
+	return data, nil
 }
 
 func (e ExecGenerator) MakeKeystore(workdir, accessKey, accessCert string) ([]byte, error) {
@@ -72,7 +80,7 @@ func (e ExecGenerator) MakeKeystore(workdir, accessKey, accessCert string) ([]by
 	return keystore, nil
 }
 
-func (e ExecGenerator) MakeTruststore(workdir, caCert string) ([]byte, error) {
+func (e ExecGenerator) MakeTruststore(workdir, caCert string, format StoreFormat) ([]byte, error) {
 	truststorePath := path.Join(workdir, "client.truststore.jks")
 	caPath := path.Join(workdir, "ca.cert")
 	err := ioutil.WriteFile(caPath, []byte(caCert), 0644)
@@ -85,6 +93,7 @@ func (e ExecGenerator) MakeTruststore(workdir, caCert string) ([]byte, error) {
 		"-file", caPath,
 		"-alias", "CA",
 		"-keystore", truststorePath,
+		"-storetype", string(format),
 		"-storepass", e.secret,
 	)
 	_, err = cmd.CombinedOutput()
@@ -1,4 +1,4 @@
-// Code generated by mockery v2.36.0. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package certificate
 
@@ -17,25 +17,29 @@ func (_m *MockGenerator) EXPECT() *MockGenerator_Expecter {
 	return &MockGenerator_Expecter{mock: &_m.Mock}
 }
 
-// MakeCredStores provides a mock function with given fields: accessKey, accessCert, caCert
-func (_m *MockGenerator) MakeCredStores(accessKey string, accessCert string, caCert string) (*CredStoreData, error) {
-	ret := _m.Called(accessKey, accessCert, caCert)
+// MakeCredStores provides a mock function with given fields: accessKey, accessCert, caCert, formats
+func (_m *MockGenerator) MakeCredStores(accessKey string, accessCert string, caCert string, formats Formats) (*CredStoreData, error) {
+	ret := _m.Called(accessKey, accessCert, caCert, formats)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MakeCredStores")
+	}
 
 	var r0 *CredStoreData
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, string) (*CredStoreData, error)); ok {
-		return rf(accessKey, accessCert, caCert)
+	if rf, ok := ret.Get(0).(func(string, string, string, Formats) (*CredStoreData, error)); ok {
+		return rf(accessKey, accessCert, caCert, formats)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, string) *CredStoreData); ok {
-		r0 = rf(accessKey, accessCert, caCert)
+	if rf, ok := ret.Get(0).(func(string, string, string, Formats) *CredStoreData); ok {
+		r0 = rf(accessKey, accessCert, caCert, formats)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*CredStoreData)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(accessKey, accessCert, caCert)
+	if rf, ok := ret.Get(1).(func(string, string, string, Formats) error); ok {
+		r1 = rf(accessKey, accessCert, caCert, formats)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -52,13 +56,14 @@ type MockGenerator_MakeCredStores_Call struct {
 //   - accessKey string
 //   - accessCert string
 //   - caCert string
-func (_e *MockGenerator_Expecter) MakeCredStores(accessKey interface{}, accessCert interface{}, caCert interface{}) *MockGenerator_MakeCredStores_Call {
-	return &MockGenerator_MakeCredStores_Call{Call: _e.mock.On("MakeCredStores", accessKey, accessCert, caCert)}
+//   - formats Formats
+func (_e *MockGenerator_Expecter) MakeCredStores(accessKey interface{}, accessCert interface{}, caCert interface{}, formats interface{}) *MockGenerator_MakeCredStores_Call {
+	return &MockGenerator_MakeCredStores_Call{Call: _e.mock.On("MakeCredStores", accessKey, accessCert, caCert, formats)}
 }
 
-func (_c *MockGenerator_MakeCredStores_Call) Run(run func(accessKey string, accessCert string, caCert string)) *MockGenerator_MakeCredStores_Call {
+func (_c *MockGenerator_MakeCredStores_Call) Run(run func(accessKey string, accessCert string, caCert string, formats Formats)) *MockGenerator_MakeCredStores_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(string), args[2].(string))
+		run(args[0].(string), args[1].(string), args[2].(string), args[3].(Formats))
 	})
 	return _c
 }
@@ -68,7 +73,7 @@ func (_c *MockGenerator_MakeCredStores_Call) Return(_a0 *CredStoreData, _a1 erro
 	return _c
 }
 
-func (_c *MockGenerator_MakeCredStores_Call) RunAndReturn(run func(string, string, string) (*CredStoreData, error)) *MockGenerator_MakeCredStores_Call {
+func (_c *MockGenerator_MakeCredStores_Call) RunAndReturn(run func(string, string, string, Formats) (*CredStoreData, error)) *MockGenerator_MakeCredStores_Call {
 	_c.Call.Return(run)
 	return _c
 }
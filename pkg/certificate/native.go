@@ -1,10 +1,14 @@
 package certificate
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
 	"software.sslmate.com/src/go-pkcs12"
 )
 
@@ -19,34 +23,56 @@ func NewNativeGenerator() Native {
 	return n
 }
 
-func (n Native) MakeCredStores(accessKey, accessCert, caCert string) (*CredStoreData, error) {
-	keystore, err := n.makeKeyStore(accessKey, accessCert)
+func (n Native) MakeCredStores(accessKey, accessCert, caCert string, formats Formats) (*CredStoreData, error) {
+	var keyStore, trustStore []byte
+	var err error
+
+	switch formats.Keystore {
+	case PKCS12:
+		keyStore, err = n.makeKeyStore(accessKey, accessCert)
+	case JKS:
+		keyStore, err = n.makeJKSKeyStore(accessKey, accessCert)
+	default:
+		err = fmt.Errorf("unsupported keystore format '%s'", formats.Keystore)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	truststore, err := n.makeTrustStore(caCert)
+	switch formats.Truststore {
+	case PKCS12:
+		trustStore, err = n.makeTrustStore(caCert)
+	case JKS:
+		trustStore, err = n.makeJKSTrustStore(caCert)
+	default:
+		err = fmt.Errorf("unsupported truststore format '%s'", formats.Truststore)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &CredStoreData{
-		Keystore:   keystore,
-		Truststore: truststore,
+	data := &CredStoreData{
+		Keystore:   keyStore,
+		Truststore: trustStore,
 		Secret:     n.secret,
-	}, nil
+	}
+	if formats.PEM {
+		addPEMBundles(data, accessKey, accessCert, caCert)
+	}
+	return data, nil
 }
 
 func (n Native) makeTrustStore(caCert string) ([]byte, error) {
-	cert, err := parseCertificate(caCert)
+	cas, err := parseCertificates(caCert)
 	if err != nil {
 		return nil, err
 	}
-	certs := []pkcs12.TrustStoreEntry{
-		{
+	certs := make([]pkcs12.TrustStoreEntry, 0, len(cas))
+	for i, cert := range cas {
+		certs = append(certs, pkcs12.TrustStoreEntry{
 			Cert:         cert,
-			FriendlyName: "ca",
-		},
+			FriendlyName: caAlias(i),
+		})
 	}
 
 	data, err := pkcs12.EncodeTrustStoreEntries(rand.Reader, certs, n.secret)
@@ -76,6 +102,71 @@ func (n Native) makeKeyStore(accessKey string, accessCert string) ([]byte, error
 	return pfxData, nil
 }
 
+func (n Native) makeJKSTrustStore(caCert string) ([]byte, error) {
+	cas, err := parseCertificates(caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := keystore.New(keystore.WithOrderedAliases())
+	for i, cert := range cas {
+		err = ks.SetTrustedCertificateEntry(caAlias(i), keystore.TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate: keystore.Certificate{
+				Type:    "X509",
+				Content: cert.Raw,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add CA to truststore: %v", err)
+		}
+	}
+
+	return n.storeJKS(ks, "truststore")
+}
+
+func (n Native) makeJKSKeyStore(accessKey string, accessCert string) ([]byte, error) {
+	cert, err := parseCertificate(accessCert)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(accessKey)
+	if err != nil {
+		return nil, err
+	}
+	pkcs8Key, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+
+	ks := keystore.New()
+	err = ks.SetPrivateKeyEntry("client", keystore.PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   pkcs8Key,
+		CertificateChain: []keystore.Certificate{
+			{
+				Type:    "X509",
+				Content: cert.Raw,
+			},
+		},
+	}, []byte(n.secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add key to keystore: %v", err)
+	}
+
+	return n.storeJKS(ks, "keystore")
+}
+
+func (n Native) storeJKS(ks keystore.KeyStore, kind string) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := ks.Store(&buffer, []byte(n.secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode jks %s: %v", kind, err)
+	}
+	return buffer.Bytes(), nil
+}
+
 func parseCertificate(rawCert string) (*x509.Certificate, error) {
 	block, _ := pem.Decode([]byte(rawCert))
 	if block == nil || block.Type != "CERTIFICATE" {
@@ -89,6 +180,48 @@ func parseCertificate(rawCert string) (*x509.Certificate, error) {
 	return cert, nil
 }
 
+// parseCertificates parses every certificate in a PEM bundle, such as a CA bundle
+func parseCertificates(rawCerts string) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, 1)
+	rest := []byte(rawCerts)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("failed to decode PEM block containing certificate")
+	}
+	return certs, nil
+}
+
+// caAlias keeps the alias "ca" for the current CA, as before CA bundles were supported
+func caAlias(index int) string {
+	if index == 0 {
+		return "ca"
+	}
+	return fmt.Sprintf("ca-%d", index)
+}
+
+// NotAfter returns the expiry of a PEM encoded certificate
+func NotAfter(rawCert string) (time.Time, error) {
+	cert, err := parseCertificate(rawCert)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
 func parsePrivateKey(rawKey string) (any, error) {
 	block, _ := pem.Decode([]byte(rawKey))
 	if block == nil || block.Type != "PRIVATE KEY" {
@@ -0,0 +1,80 @@
+package certificate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nais/aivenator/pkg/certificate/certtest"
+)
+
+func makeKeyPair(t *testing.T, commonName string) (string, string) {
+	keyPair := certtest.SelfSigned(t, commonName, time.Now().Add(time.Hour))
+	return keyPair.Key, keyPair.Certificate
+}
+
+func TestNativeJKS(t *testing.T) {
+	accessKey, accessCert := makeKeyPair(t, "client")
+	_, caCert := makeKeyPair(t, "ca")
+
+	data, err := NewNativeGenerator().MakeCredStores(accessKey, accessCert, caCert, Formats{
+		Keystore:   JKS,
+		Truststore: JKS,
+	})
+	require.NoError(t, err)
+
+	keyStore := keystore.New()
+	require.NoError(t, keyStore.Load(bytes.NewReader(data.Keystore), []byte(data.Secret)))
+	assert.True(t, keyStore.IsPrivateKeyEntry("client"))
+
+	trustStore := keystore.New()
+	require.NoError(t, trustStore.Load(bytes.NewReader(data.Truststore), []byte(data.Secret)))
+	assert.True(t, trustStore.IsTrustedCertificateEntry("ca"))
+
+	assert.Nil(t, data.KeyBundle)
+	assert.Nil(t, data.CertificateChain)
+}
+
+func TestNativeMultipleCAs(t *testing.T) {
+	accessKey, accessCert := makeKeyPair(t, "client")
+	_, caCert := makeKeyPair(t, "ca")
+	_, previousCert := makeKeyPair(t, "previous-ca")
+
+	data, err := NewNativeGenerator().MakeCredStores(accessKey, accessCert, caCert+previousCert, Formats{
+		Keystore:   PKCS12,
+		Truststore: JKS,
+	})
+	require.NoError(t, err)
+
+	trustStore := keystore.New()
+	require.NoError(t, trustStore.Load(bytes.NewReader(data.Truststore), []byte(data.Secret)))
+	assert.True(t, trustStore.IsTrustedCertificateEntry("ca"))
+	assert.True(t, trustStore.IsTrustedCertificateEntry("ca-1"))
+}
+
+func TestNativePEMBundles(t *testing.T) {
+	accessKey, accessCert := makeKeyPair(t, "client")
+	_, caCert := makeKeyPair(t, "ca")
+	formats := DefaultFormats()
+	formats.PEM = true
+
+	data, err := NewNativeGenerator().MakeCredStores(accessKey, accessCert, caCert, formats)
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.TrimSpace(accessCert)+"\n"+strings.TrimSpace(accessKey)+"\n", string(data.KeyBundle))
+	assert.Equal(t, strings.TrimSpace(accessCert)+"\n"+strings.TrimSpace(caCert)+"\n", string(data.CertificateChain))
+}
+
+func TestParseStoreFormat(t *testing.T) {
+	format, err := ParseStoreFormat("jks")
+	assert.NoError(t, err)
+	assert.Equal(t, JKS, format)
+
+	_, err = ParseStoreFormat("pem")
+	assert.Error(t, err)
+}
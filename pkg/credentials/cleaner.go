@@ -6,6 +6,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"time"
 
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	"github.com/nais/liberator/pkg/kubernetes"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/pkg/annotations"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
 	"github.com/nais/aivenator/pkg/metrics"
 	"github.com/nais/aivenator/pkg/utils"
 )
@@ -80,6 +82,8 @@ func (j *Cleaner) CleanUnusedSecrets(ctx context.Context) error {
 		return err
 	}
 
+	observeCertificateExpiry(secrets)
+
 	counts, err := j.cleanUnusedSecrets(ctx, secrets, objects)
 	if err != nil {
 		return err
@@ -126,6 +130,27 @@ func (j *Cleaner) cleanUnusedSecrets(ctx context.Context, secrets corev1.SecretL
 	return &counts, nil
 }
 
+func observeCertificateExpiry(secrets corev1.SecretList) {
+	earliest := make(map[string]time.Time)
+	for _, secret := range secrets.Items {
+		expiry, ok := kafka.EarliestCertificateExpiry(secret.GetAnnotations())
+		if !ok {
+			continue
+		}
+		current, seen := earliest[secret.GetNamespace()]
+		if !seen || expiry.Before(current) {
+			earliest[secret.GetNamespace()] = expiry
+		}
+	}
+
+	metrics.KafkaCertificateEarliestExpiry.Reset()
+	for namespace, expiry := range earliest {
+		metrics.KafkaCertificateEarliestExpiry.With(prometheus.Labels{
+			metrics.LabelNamespace: namespace,
+		}).Set(float64(expiry.Unix()))
+	}
+}
+
 func inUse(object client.Object, secretName string) (bool, error) {
 	var volumes []corev1.Volume
 	switch t := object.(type) {
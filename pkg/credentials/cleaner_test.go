@@ -10,6 +10,7 @@ import (
 	"time"
 
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -20,6 +21,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/handlers/kafka"
+	"github.com/nais/aivenator/pkg/metrics"
 	"github.com/nais/aivenator/pkg/utils"
 )
 
@@ -352,6 +355,25 @@ func (suite *JanitorTestSuite) TestErrors() {
 	}
 }
 
+func (suite *JanitorTestSuite) TestObserveCertificateExpiry() {
+	earliest := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	mySecret := makeSecret(UnusedSecret, MyNamespace, constants.AivenatorSecretType, MyAppName)
+	mySecret.SetAnnotations(map[string]string{
+		kafka.CertificateExpiryAnnotation:                 earliest.Add(time.Hour).Format(time.RFC3339),
+		"other-pool." + kafka.CertificateExpiryAnnotation: earliest.Format(time.RFC3339),
+	})
+	otherSecret := makeSecret(SecretUsedByPod, MyNamespace, constants.AivenatorSecretType, NotMyAppName)
+	otherSecret.SetAnnotations(map[string]string{
+		kafka.CertificateExpiryAnnotation: earliest.Add(24 * time.Hour).Format(time.RFC3339),
+	})
+	secrets := corev1.SecretList{Items: []corev1.Secret{*mySecret, *otherSecret}}
+
+	observeCertificateExpiry(secrets)
+
+	suite.Equal(float64(earliest.Unix()), testutil.ToFloat64(metrics.KafkaCertificateEarliestExpiry.WithLabelValues(MyNamespace)))
+	suite.Equal(1, testutil.CollectAndCount(metrics.KafkaCertificateEarliestExpiry))
+}
+
 func makePodForSecret(secretName string) *corev1.Pod {
 	return &corev1.Pod{
 		Spec: corev1.PodSpec{
@@ -12,12 +12,17 @@ import (
 
 	aivenv1 "github.com/aiven/aiven-go-client"
 	"github.com/aiven/aiven-go-client/v2"
+	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/handlers/kafka"
+	"github.com/nais/aivenator/pkg/handlers/kafkaconnect"
 	"github.com/nais/aivenator/pkg/handlers/opensearch"
 	"github.com/nais/aivenator/pkg/handlers/secret"
+	"github.com/nais/aivenator/pkg/policy"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Handler interface {
@@ -29,14 +34,15 @@ type Manager struct {
 	handlers []Handler
 }
 
-func NewManager(ctx context.Context, aiven *aiven.Client, kafkaProjects []string, mainProjectName string, logger *log.Entry, aivenv1 *aivenv1.Client) Manager {
+func NewManager(ctx context.Context, aiven *aiven.Client, projectManager project.ProjectManager, kafkaProjects []string, mainProjectName string, kafkaConfig kafka.Config, openSearchConfig opensearch.Config, redisConfig redis.Config, addressRoutes service.AddressRoutes, names service.NameResolver, caBundler project.CABundler, kubeClient client.Reader, authorizer policy.Authorizer, logger *log.Entry, aivenv1 *aivenv1.Client) Manager {
 	return Manager{
 		handlers: []Handler{
-			secret.NewHandler(aiven, mainProjectName),
-			kafka.NewKafkaHandler(ctx, aiven, kafkaProjects, logger, aivenv1),
-			opensearch.NewOpenSearchHandler(ctx, aiven, mainProjectName),
-			redis.NewRedisHandler(ctx, aiven, mainProjectName),
-			influxdb.NewInfluxDBHandler(ctx, aiven, mainProjectName),
+			secret.NewHandler(projectManager, mainProjectName, caBundler),
+			kafka.NewKafkaHandler(ctx, aiven, projectManager, kafkaProjects, kafkaConfig, addressRoutes, caBundler, kubeClient, authorizer, logger, aivenv1),
+			opensearch.NewOpenSearchHandler(ctx, aiven, mainProjectName, addressRoutes, names, openSearchConfig, kubeClient, authorizer),
+			redis.NewRedisHandler(ctx, aiven, mainProjectName, addressRoutes, names, redisConfig, authorizer),
+			influxdb.NewInfluxDBHandler(ctx, aiven, mainProjectName, addressRoutes, names, authorizer),
+			kafkaconnect.NewKafkaConnectHandler(ctx, aiven, mainProjectName, addressRoutes, kubeClient, authorizer),
 		},
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/service"
+	"github.com/nais/aivenator/pkg/annotations"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	log "github.com/sirupsen/logrus"
@@ -11,8 +13,9 @@ import (
 )
 
 const (
-	ServiceUserAnnotation = "influxdb.aiven.nais.io/serviceUser"
-	ProjectAnnotation     = "influxdb.aiven.nais.io/project"
+	ServiceUserAnnotation  = "influxdb.aiven.nais.io/serviceUser"
+	ProjectAnnotation      = "influxdb.aiven.nais.io/project"
+	AddressRouteAnnotation = "influxdb.aiven.nais.io/addressRoute"
 )
 
 // Environment variables
@@ -23,15 +26,19 @@ const (
 	InfluxDBName     = "INFLUXDB_NAME"
 )
 
-func NewInfluxDBHandler(ctx context.Context, aiven *aiven.Client, projectName string) InfluxDBHandler {
+func NewInfluxDBHandler(ctx context.Context, aiven *aiven.Client, projectName string, addressRoutes service.AddressRoutes, names service.NameResolver, authorizer policy.Authorizer) InfluxDBHandler {
 	return InfluxDBHandler{
-		service:     service.NewManager(aiven.Services),
+		service:     service.NewManager(aiven.Services, addressRoutes),
+		names:       names,
+		authorizer:  authorizer,
 		projectName: projectName,
 	}
 }
 
 type InfluxDBHandler struct {
 	service     service.ServiceManager
+	names       service.NameResolver
+	authorizer  policy.Authorizer
 	projectName string
 }
 
@@ -42,17 +49,27 @@ func (h InfluxDBHandler) Apply(ctx context.Context, application *aiven_nais_io_v
 	}
 
 	spec := application.Spec.InfluxDB
-	serviceName := spec.Instance
+	serviceName, err := h.names.ResolveServiceName(service.InfluxDB, application, spec.Instance)
+	if err != nil {
+		utils.LocalFail("ResolveServiceName", application, err, logger)
+		return err
+	}
 
 	logger = logger.WithFields(log.Fields{
 		"project": h.projectName,
 		"service": serviceName,
 	})
 
+	err = policy.Check(h.authorizer, application, policy.InfluxDB, serviceName, "", logger)
+	if err != nil {
+		return err
+	}
+
 	addresses, err := h.service.GetServiceAddresses(ctx, h.projectName, serviceName)
 	if err != nil {
 		return utils.AivenFail("GetService", application, err, true, logger)
 	}
+	annotations.SetAddressRoute(secret, AddressRouteAnnotation, addresses.Route(service.ComponentInfluxDB))
 
 	aivenService, err := h.service.Get(ctx, h.projectName, serviceName)
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/service"
+	"github.com/nais/aivenator/pkg/policy"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -63,6 +64,8 @@ var _ = Describe("influxdb.Handler", func() {
 		}
 		influxdbHandler = InfluxDBHandler{
 			service:     mocks.serviceManager,
+			names:       service.NewDefaultNameResolver(),
+			authorizer:  policy.AllowAll{},
 			projectName: projectName,
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
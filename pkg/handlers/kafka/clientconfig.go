@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Client configuration files in secret
+const (
+	KafkaClientProperties = "client.properties"
+	KafkaLibrdkafkaConfig = "kafka.conf"
+	KafkaKCatConfig       = "kcat.conf"
+)
+
+var configFiles = []string{KafkaClientProperties, KafkaLibrdkafkaConfig, KafkaKCatConfig}
+
+// DefaultClientConfigMountPath is where NAIS mounts the Kafka credentials in the application container
+const DefaultClientConfigMountPath = "/var/run/secrets/nais.io/kafka"
+
+type clientConfig struct {
+	authentication    string
+	brokers           string
+	schemaRegistry    string
+	username          string
+	password          string
+	credStorePassword string
+	stores            storeFiles
+}
+
+type property struct {
+	key   string
+	value string
+}
+
+// render creates the configuration files for a pool, referencing the other files in the secret as they appear when mounted at mountPath
+func (c clientConfig) render(mountPath string, keys poolKeys) map[string][]byte {
+	filePath := func(key string) string {
+		return path.Join(mountPath, key)
+	}
+	caPath := filePath(keys.envKey(KafkaCA))
+
+	java := []property{
+		{"bootstrap.servers", c.brokers},
+	}
+	librdkafka := []property{
+		{"bootstrap.servers", c.brokers},
+	}
+
+	if c.authentication == AuthenticationSASL {
+		java = append(java,
+			property{"security.protocol", "SASL_SSL"},
+			property{"sasl.mechanism", saslMechanism},
+			property{"sasl.jaas.config", fmt.Sprintf(`org.apache.kafka.common.security.scram.ScramLoginModule required username="%s" password="%s";`, c.username, c.password)},
+			property{"ssl.truststore.type", "PEM"},
+			property{"ssl.truststore.location", caPath},
+		)
+		librdkafka = append(librdkafka,
+			property{"security.protocol", "sasl_ssl"},
+			property{"sasl.mechanisms", saslMechanism},
+			property{"sasl.username", c.username},
+			property{"sasl.password", c.password},
+			property{"ssl.ca.location", caPath},
+		)
+	} else {
+		java = append(java,
+			property{"security.protocol", "SSL"},
+			property{"ssl.keystore.type", string(c.stores.Keystore)},
+			property{"ssl.keystore.location", filePath(keys.fileKey(c.stores.keystore))},
+			property{"ssl.keystore.password", c.credStorePassword},
+			property{"ssl.key.password", c.credStorePassword},
+			property{"ssl.truststore.type", string(c.stores.Truststore)},
+			property{"ssl.truststore.location", filePath(keys.fileKey(c.stores.truststore))},
+			property{"ssl.truststore.password", c.credStorePassword},
+		)
+		librdkafka = append(librdkafka,
+			property{"security.protocol", "ssl"},
+			property{"ssl.ca.location", caPath},
+			property{"ssl.certificate.location", filePath(keys.envKey(KafkaCertificate))},
+			property{"ssl.key.location", filePath(keys.envKey(KafkaPrivateKey))},
+		)
+	}
+
+	if c.schemaRegistry != "" {
+		java = append(java,
+			property{"schema.registry.url", c.schemaRegistry},
+			property{"basic.auth.credentials.source", "USER_INFO"},
+			property{"basic.auth.user.info", fmt.Sprintf("%s:%s", c.username, c.password)},
+		)
+	}
+
+	return map[string][]byte{
+		KafkaClientProperties: formatProperties(java),
+		KafkaLibrdkafkaConfig: formatProperties(librdkafka),
+		KafkaKCatConfig:       formatProperties(librdkafka),
+	}
+}
+
+func formatProperties(properties []property) []byte {
+	builder := strings.Builder{}
+	for _, p := range properties {
+		builder.WriteString(fmt.Sprintf("%s=%s\n", p.key, p.value))
+	}
+	return []byte(builder.String())
+}
@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/certificate"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+func (h KafkaHandler) certificateExpiring(aivenUser *aiven.ServiceUser, logger log.FieldLogger) bool {
+	if h.config.CertificateReissueWindow <= 0 {
+		return false
+	}
+	notAfter, err := certificate.NotAfter(aivenUser.AccessCert)
+	if err != nil {
+		logger.Warnf("Unable to check certificate expiry for service user %s: %v", aivenUser.Username, err)
+		return false
+	}
+	return time.Now().Add(h.config.CertificateReissueWindow).After(notAfter)
+}
+
+// reissueServiceUser replaces a service user whose certificate is about to expire with a new service user.
+// Running pods keep using the replaced service user until they restart with the new secret, so it is only deleted along
+// with the secret, or at the next reissue, long after the pods have moved on.
+func (h KafkaHandler) reissueServiceUser(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, expiringServiceUserName, projectName, serviceName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	serviceUserName, err := h.serviceUserName(application, expiringServiceUserName)
+	if err != nil {
+		err = fmt.Errorf("unable to create service user name: %s %w", err, utils.UnrecoverableError)
+		utils.LocalFail("ServiceUserNameWithSuffix", application, err, logger)
+		return nil, err
+	}
+	logger.Infof("Certificate for service user %s expires within %v, replacing it with %s", expiringServiceUserName, h.config.CertificateReissueWindow, serviceUserName)
+
+	aivenUser, err := h.getOrCreateServiceUser(ctx, application, serviceUserName, projectName, serviceName, false, secret, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation := keys.annotation(ReplacedServiceUserAnnotation)
+	if previous, ok := secret.GetAnnotations()[annotation]; ok && previous != expiringServiceUserName {
+		err = h.deleteServiceUser(ctx, previous, projectName, serviceUserResourcesFor(secret.GetAnnotations(), keys.annotation("")), logger)
+		if err != nil {
+			return nil, utils.AivenFail("DeleteServiceUser", application, err, false, logger)
+		}
+	}
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		annotation: expiringServiceUserName,
+	}))
+	metrics.KafkaCertificatesReissued.With(prometheus.Labels{metrics.LabelPool: projectName}).Inc()
+	return aivenUser, nil
+}
+
+func recordCertificateExpiry(aivenUser *aiven.ServiceUser, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) {
+	notAfter, err := certificate.NotAfter(aivenUser.AccessCert)
+	if err != nil {
+		logger.Warnf("Unable to record certificate expiry for service user %s: %v", aivenUser.Username, err)
+		delete(secret.GetAnnotations(), keys.annotation(CertificateExpiryAnnotation))
+		return
+	}
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		keys.annotation(CertificateExpiryAnnotation): notAfter.Format(time.RFC3339),
+	}))
+}
+
+// setRenewAfter tells the reconciler when the secret needs to be synchronized again, to have its certificates reissued or deferred migrations retried
+func (h KafkaHandler) setRenewAfter(secret *v1.Secret, applied map[string]poolKeys, logger log.FieldLogger) {
+	delete(secret.GetAnnotations(), constants.AivenatorRenewAfterAnnotation)
+
+	renewAfter := h.certificateRenewAfter(secret, applied, logger)
+	if retry, ok := h.migrationRetry(secret, applied); ok && (renewAfter.IsZero() || retry.Before(renewAfter)) {
+		renewAfter = retry
+	}
+	if renewAfter.IsZero() {
+		return
+	}
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		constants.AivenatorRenewAfterAnnotation: renewAfter.Format(time.RFC3339),
+	}))
+}
+
+// certificateRenewAfter is when the earliest expiring certificate enters the reissue window, zero if there is no such time
+func (h KafkaHandler) certificateRenewAfter(secret *v1.Secret, applied map[string]poolKeys, logger log.FieldLogger) time.Time {
+	if h.config.CertificateReissueWindow <= 0 {
+		return time.Time{}
+	}
+
+	var earliest time.Time
+	for _, keys := range applied {
+		expiry, err := utils.Parse(secret.GetAnnotations()[keys.annotation(CertificateExpiryAnnotation)])
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	if earliest.IsZero() {
+		return time.Time{}
+	}
+
+	renewAfter := earliest.Add(-h.config.CertificateReissueWindow)
+	if utils.Expired(renewAfter) {
+		logger.Warnf("Certificate expiring at %s is already within the reissue window of %v", earliest.Format(time.RFC3339), h.config.CertificateReissueWindow)
+		return time.Time{}
+	}
+	return renewAfter
+}
+
+// EarliestCertificateExpiry finds the earliest certificate expiry recorded in the annotations of a secret, across all pools
+func EarliestCertificateExpiry(annotations map[string]string) (time.Time, bool) {
+	var earliest time.Time
+	for key, value := range annotations {
+		if !strings.HasSuffix(key, CertificateExpiryAnnotation) {
+			continue
+		}
+		expiry, err := utils.Parse(value)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
@@ -2,26 +2,28 @@ package kafka
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
-	"hash/crc32"
-	"os"
 	"time"
 
 	aivenv1 "github.com/aiven/aiven-go-client"
 	"github.com/aiven/aiven-go-client/v2"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
-	kafka_nais_io_v1 "github.com/nais/liberator/pkg/apis/kafka.nais.io/v1"
 	"github.com/nais/liberator/pkg/strings"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	"k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/aiven/quota"
+	"github.com/nais/aivenator/pkg/aiven/schemaregistry"
 	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/annotations"
 	"github.com/nais/aivenator/pkg/certificate"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	liberator_service "github.com/nais/liberator/pkg/aiven/service"
 )
@@ -39,37 +41,125 @@ const (
 	KafkaSecretUpdated     = "KAFKA_SECRET_UPDATED"
 	KafkaKeystore          = "client.keystore.p12"
 	KafkaTruststore        = "client.truststore.jks"
+	KafkaJKSKeystore       = "client.keystore.jks"
+	KafkaPKCS12Truststore  = "client.truststore.p12"
+	KafkaKeyBundle         = "client.bundle.pem"
+	KafkaCertificateChain  = "client.chain.pem"
+	KafkaSASLBrokers       = "KAFKA_SASL_BROKERS"
+	KafkaSASLUsername      = "KAFKA_SASL_USERNAME"
+	KafkaSASLPassword      = "KAFKA_SASL_PASSWORD"
+	KafkaSASLMechanism     = "KAFKA_SASL_MECHANISM"
+	KafkaRESTURI           = "KAFKA_REST_URI"
+	KafkaRESTUser          = "KAFKA_REST_USER"
+	KafkaRESTPassword      = "KAFKA_REST_PASSWORD"
 )
 
 // Annotations
 const (
-	ServiceUserAnnotation = "kafka.aiven.nais.io/serviceUser"
-	PoolAnnotation        = "kafka.aiven.nais.io/pool"
+	ServiceUserAnnotation    = "kafka.aiven.nais.io/serviceUser"
+	PoolAnnotation           = "kafka.aiven.nais.io/pool"
+	AuthenticationAnnotation = "kafka.aiven.nais.io/authentication"
+	// Comma separated list of pools provisioned in addition to the pool in the spec, using pool-prefixed keys
+	AdditionalPoolsAnnotation = "kafka.aiven.nais.io/additionalPools"
+	// Expiry of the service user certificate in the secret, set by aivenator
+	CertificateExpiryAnnotation = "kafka.aiven.nais.io/certificateExpiry"
+	// Set to "true" to add ready-to-use client configuration files to the secret
+	ClientConfigAnnotation = "kafka.aiven.nais.io/clientConfig"
+	// Comma separated key=value overrides of the default quota, e.g. "producerByteRate=1048576,requestPercentage=25"
+	QuotaAnnotation = "kafka.aiven.nais.io/quota"
+	// Quota applied to the service user in the secret, set by aivenator
+	AppliedQuotaAnnotation = "kafka.aiven.nais.io/appliedQuota"
+	// Format of the keystore, "pkcs12" (default) or "jks"
+	KeystoreFormatAnnotation = "kafka.aiven.nais.io/keystoreFormat"
+	// Format of the truststore, "pkcs12" or "jks". Without it, a PKCS12 truststore is written to client.truststore.jks
+	TruststoreFormatAnnotation = "kafka.aiven.nais.io/truststoreFormat"
+	// Set to "true" to add PEM bundles of the client certificate with its key and with the CA
+	PEMBundlesAnnotation = "kafka.aiven.nais.io/pemBundles"
+	// Fingerprint of the pool CA in the secret, and the previous CA still trusted after a rotation, set by aivenator
+	CAFingerprintAnnotation   = "kafka.aiven.nais.io/caFingerprint"
+	PreviousCAAnnotation      = "kafka.aiven.nais.io/previousCA"
+	PreviousCAUntilAnnotation = "kafka.aiven.nais.io/previousCAUntil"
+	AddressRouteAnnotation    = "kafka.aiven.nais.io/addressRoute"
+	// Comma separated list of schema registry subject prefixes the application may write to, e.g. "myteam.orders"
+	SchemaRegistryWriteAnnotation = "kafka.aiven.nais.io/schemaRegistryWrite"
+	// Schema registry ACL entries of the service user in the secret, set by aivenator
+	AppliedSchemaRegistryACLAnnotation = "kafka.aiven.nais.io/appliedSchemaRegistryACL"
+	// Set to "true" to add the Kafka REST address and basic-auth credentials to the secret
+	RESTAnnotation = "kafka.aiven.nais.io/rest"
+	// Service user with a legacy name replaced by a migration, deleted along with the secret, set by aivenator
+	LegacyServiceUserAnnotation = "kafka.aiven.nais.io/legacyServiceUser"
+	// Service user replaced when its certificate was about to expire, deleted along with the secret, set by aivenator
+	ReplacedServiceUserAnnotation = "kafka.aiven.nais.io/replacedServiceUser"
+	// Naming convention of a service user whose migration is held back by the rate limit, set by aivenator
+	MigrationDeferredAnnotation = "kafka.aiven.nais.io/migrationDeferred"
 )
 
-var clusterName = ""
+// Authentication modes, selected with AuthenticationAnnotation on the AivenApplication
+const (
+	AuthenticationMTLS = "mtls"
+	AuthenticationSASL = "sasl"
+)
+
+const saslMechanism = "SCRAM-SHA-512"
+
+// Keys only used by one of the authentication modes, removed when switching to the other
+var (
+	mTLSKeys  = []string{KafkaCertificate, KafkaPrivateKey, KafkaBrokers, KafkaCredStorePassword}
+	mTLSFiles = []string{KafkaKeystore, KafkaJKSKeystore, KafkaTruststore, KafkaPKCS12Truststore, KafkaKeyBundle, KafkaCertificateChain}
+	saslKeys  = []string{KafkaSASLBrokers, KafkaSASLUsername, KafkaSASLPassword, KafkaSASLMechanism}
+)
 
-func NewKafkaHandler(ctx context.Context, aiven *aiven.Client, projects []string, logger *log.Entry, aivenv1 *aivenv1.Client) KafkaHandler {
+// Config holds the operator level settings for the Kafka handler
+type Config struct {
+	// ClientConfigMountPath is where the secret is mounted in the application, used for file paths in client configuration files
+	ClientConfigMountPath string
+	// CertificateReissueWindow is how long before expiry a service user is replaced by one with a new certificate, zero disables reissue
+	CertificateReissueWindow time.Duration
+	// Quota is the default quota for service users, zero values are left unlimited
+	Quota quota.Quota
+	// ClusterName is part of the service user suffix, keeping names from different clusters sharing a pool apart
+	ClusterName string
+	// ServiceUserSuffixLength is the number of characters in the service user suffix, fewer when the name would be too long
+	ServiceUserSuffixLength int
+	// Migration of service users with legacy names
+	Migration MigrationConfig
+}
+
+func NewKafkaHandler(ctx context.Context, aiven *aiven.Client, projectManager project.ProjectManager, projects []string, config Config, addressRoutes service.AddressRoutes, caBundler project.CABundler, secrets client.Reader, authorizer policy.Authorizer, logger *log.Entry, aivenv1 *aivenv1.Client) KafkaHandler {
 	generator := certificate.NewNativeGenerator()
 	handler := KafkaHandler{
-		project:      project.NewManager(aiven.CA),
-		serviceuser:  serviceuser.NewManager(ctx, aiven.ServiceUsers),
-		service:      service.NewManager(aiven.Services),
-		generator:    generator,
-		nameResolver: liberator_service.NewCachedNameResolver(aivenv1.Services),
-		projects:     projects,
+		project:           projectManager,
+		caBundler:         caBundler,
+		quota:             quota.NewManager(aiven),
+		schemaRegistry:    schemaregistry.NewManager(aiven.KafkaSchemaRegistryACLs),
+		serviceuser:       serviceuser.NewManager(ctx, aiven.ServiceUsers),
+		service:           service.NewManager(aiven.Services, addressRoutes),
+		generator:         generator,
+		nameResolver:      liberator_service.NewCachedNameResolver(aivenv1.Services),
+		secrets:           secrets,
+		authorizer:        authorizer,
+		migrationLimiters: newMigrationLimiters(projects, config.Migration),
+		projects:          projects,
+		config:            config,
 	}
 	handler.StartUserCounter(ctx, logger)
 	return handler
 }
 
 type KafkaHandler struct {
-	project      project.ProjectManager
-	serviceuser  serviceuser.ServiceUserManager
-	service      service.ServiceManager
-	generator    certificate.Generator
-	nameResolver liberator_service.NameResolver
-	projects     []string
+	project           project.ProjectManager
+	caBundler         project.CABundler
+	quota             quota.QuotaManager
+	schemaRegistry    schemaregistry.ACLManager
+	serviceuser       serviceuser.ServiceUserManager
+	service           service.ServiceManager
+	generator         certificate.Generator
+	nameResolver      liberator_service.NameResolver
+	secrets           client.Reader
+	authorizer        policy.Authorizer
+	migrationLimiters map[string]*rate.Limiter
+	projects          []string
+	config            Config
 }
 
 func (h KafkaHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, secret *v1.Secret, logger log.FieldLogger) error {
@@ -84,7 +174,36 @@ func (h KafkaHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.A
 		return nil
 	}
 
-	serviceName, err := h.nameResolver.ResolveKafkaServiceName(application.Spec.Kafka.Pool)
+	err := h.applyPool(ctx, application, projectName, poolKeys{}, secret, logger)
+	if err != nil {
+		return err
+	}
+	applied := map[string]poolKeys{projectName: {}}
+
+	for _, additionalPool := range additionalPools(application) {
+		keys := prefixedPoolKeys(additionalPool)
+		err = h.applyPool(ctx, application, additionalPool, keys, secret, logger)
+		if err != nil {
+			return err
+		}
+		applied[additionalPool] = keys
+	}
+
+	h.setRenewAfter(secret, applied, logger)
+	reportQuotas(application, secret, applied)
+
+	controllerutil.AddFinalizer(secret, constants.AivenatorFinalizer)
+
+	return nil
+}
+
+func (h KafkaHandler) applyPool(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, projectName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) error {
+	err := policy.Check(h.authorizer, application, policy.Kafka, projectName, "", logger)
+	if err != nil {
+		return err
+	}
+
+	serviceName, err := h.nameResolver.ResolveKafkaServiceName(projectName)
 	if err != nil {
 		return utils.AivenFail("ResolveServiceName", application, err, false, logger)
 	}
@@ -100,72 +219,220 @@ func (h KafkaHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.A
 		return err
 	}
 
+	authentication, err := authenticationMode(application)
+	if err != nil {
+		utils.LocalFail("ValidateAuthentication", application, err, logger)
+		return err
+	}
+
+	formats, err := storeFormats(application)
+	if err != nil {
+		utils.LocalFail("ValidateStoreFormats", application, err, logger)
+		return err
+	}
+
+	desiredQuota, err := h.desiredQuota(application)
+	if err != nil {
+		utils.LocalFail("ValidateQuota", application, err, logger)
+		return err
+	}
+
+	desiredSchemaACLs, err := desiredSchemaRegistryACLs(application)
+	if err != nil {
+		utils.LocalFail("ValidateSchemaRegistryACL", application, err, logger)
+		return err
+	}
+
 	addresses, err := h.service.GetServiceAddresses(ctx, projectName, serviceName)
 	if err != nil {
 		return utils.AivenFail("GetService", application, err, false, logger)
 	}
+	// Nothing is provisioned for applications the pool can not serve, since no secret would be saved to clean it up
+	if authentication == AuthenticationSASL && addresses.KafkaSASL == "" {
+		err := fmt.Errorf("pool %s has no SASL listener enabled: %w", projectName, utils.UnrecoverableError)
+		utils.LocalFail("ResolveSASLAddress", application, err, logger)
+		return err
+	}
+	if application.GetAnnotations()[RESTAnnotation] == "true" && addresses.KafkaREST == "" {
+		err := fmt.Errorf("pool %s has no Kafka REST enabled: %w", projectName, utils.UnrecoverableError)
+		utils.LocalFail("ResolveRESTAddress", application, err, logger)
+		return err
+	}
+	annotations.SetAddressRoute(secret, keys.annotation(AddressRouteAnnotation), addresses.Route(addressComponents(application, authentication, addresses)...))
 
-	ca, err := h.project.GetCA(ctx, projectName)
+	projectCA, err := h.project.GetCA(ctx, projectName)
 	if err != nil {
 		return utils.AivenFail("GetCA", application, err, false, logger)
 	}
+	ca := h.caBundler.Bundle(projectName, projectCA, string(secret.Data[keys.envKey(KafkaCA)]), project.CAAnnotations{
+		Fingerprint:   keys.annotation(CAFingerprintAnnotation),
+		Previous:      keys.annotation(PreviousCAAnnotation),
+		PreviousUntil: keys.annotation(PreviousCAUntilAnnotation),
+	}, secret, logger)
 
-	aivenUser, err := h.provideServiceUser(ctx, application, projectName, serviceName, secret, logger)
+	aivenUser, err := h.provideServiceUser(ctx, application, authentication, projectName, serviceName, keys, secret, logger)
 	if err != nil {
 		return err
 	}
 
+	err = h.applyQuota(ctx, application, desiredQuota, aivenUser.Username, projectName, serviceName, keys, secret, logger)
+	if err != nil {
+		return err
+	}
+
+	if addresses.SchemaRegistry != "" {
+		err = h.applySchemaRegistryACLs(ctx, application, desiredSchemaACLs, aivenUser.Username, projectName, serviceName, keys, secret, logger)
+		if err != nil {
+			return err
+		}
+	}
+
 	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
-		ServiceUserAnnotation: aivenUser.Username,
-		PoolAnnotation:        application.Spec.Kafka.Pool,
+		keys.annotation(ServiceUserAnnotation): aivenUser.Username,
+		keys.annotation(PoolAnnotation):        projectName,
 	}))
 	logger.Infof("Created service user %s", aivenUser.Username)
 
-	credStore, err := h.generator.MakeCredStores(aivenUser.AccessKey, aivenUser.AccessCert, ca)
+	secret.StringData = utils.MergeStringMap(secret.StringData, keys.env(map[string]string{
+		KafkaSchemaRegistry: addresses.SchemaRegistry,
+		KafkaSchemaUser:     aivenUser.Username,
+		KafkaSchemaPassword: aivenUser.Password,
+		KafkaCA:             ca,
+		KafkaSecretUpdated:  time.Now().Format(time.RFC3339),
+	}))
+
+	config := clientConfig{
+		authentication: authentication,
+		schemaRegistry: addresses.SchemaRegistry,
+		username:       aivenUser.Username,
+		password:       aivenUser.Password,
+	}
+
+	if authentication == AuthenticationSASL {
+		secret.StringData = utils.MergeStringMap(secret.StringData, keys.env(map[string]string{
+			KafkaSASLBrokers:   addresses.KafkaSASL,
+			KafkaSASLUsername:  aivenUser.Username,
+			KafkaSASLPassword:  aivenUser.Password,
+			KafkaSASLMechanism: saslMechanism,
+		}))
+		config.brokers = addresses.KafkaSASL
+		delete(secret.GetAnnotations(), keys.annotation(CertificateExpiryAnnotation))
+		keys.removeEnv(secret, mTLSKeys...)
+		keys.removeFiles(secret, mTLSFiles...)
+	} else {
+		credStore, err := h.applyClientCertificate(application, aivenUser, addresses, ca, formats, keys, secret, logger)
+		if err != nil {
+			return err
+		}
+		config.brokers = addresses.ServiceURI
+		config.credStorePassword = credStore.Secret
+		config.stores = formats
+		recordCertificateExpiry(aivenUser, keys, secret, logger)
+		keys.removeEnv(secret, saslKeys...)
+	}
+
+	applyREST(application, aivenUser, addresses, keys, secret)
+
+	if application.GetAnnotations()[ClientConfigAnnotation] == "true" {
+		secret.Data = utils.MergeByteMap(secret.Data, keys.files(config.render(h.clientConfigMountPath(), keys)))
+	} else {
+		keys.removeFiles(secret, configFiles...)
+	}
+
+	return nil
+}
+
+func (h KafkaHandler) applyClientCertificate(application *aiven_nais_io_v1.AivenApplication, aivenUser *aiven.ServiceUser, addresses *service.ServiceAddresses, ca string, formats storeFiles, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) (*certificate.CredStoreData, error) {
+	credStore, err := h.generator.MakeCredStores(aivenUser.AccessKey, aivenUser.AccessCert, ca, formats.Formats)
 	if err != nil {
 		utils.LocalFail("CreateCredStores", application, err, logger)
-		return err
+		return nil, err
 	}
 
-	secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
+	secret.StringData = utils.MergeStringMap(secret.StringData, keys.env(map[string]string{
 		KafkaCertificate:       aivenUser.AccessCert,
 		KafkaPrivateKey:        aivenUser.AccessKey,
 		KafkaBrokers:           addresses.ServiceURI,
-		KafkaSchemaRegistry:    addresses.SchemaRegistry,
-		KafkaSchemaUser:        aivenUser.Username,
-		KafkaSchemaPassword:    aivenUser.Password,
-		KafkaCA:                ca,
 		KafkaCredStorePassword: credStore.Secret,
-		KafkaSecretUpdated:     time.Now().Format(time.RFC3339),
-	})
+	}))
 
-	secret.Data = utils.MergeByteMap(secret.Data, map[string][]byte{
-		KafkaKeystore:   credStore.Keystore,
-		KafkaTruststore: credStore.Truststore,
-	})
+	files := map[string][]byte{
+		formats.keystore:   credStore.Keystore,
+		formats.truststore: credStore.Truststore,
+	}
+	if formats.PEM {
+		files[KafkaKeyBundle] = credStore.KeyBundle
+		files[KafkaCertificateChain] = credStore.CertificateChain
+	}
+	for _, file := range mTLSFiles {
+		if _, ok := files[file]; !ok {
+			keys.removeFiles(secret, file)
+		}
+	}
+	secret.Data = utils.MergeByteMap(secret.Data, keys.files(files))
 
-	controllerutil.AddFinalizer(secret, constants.AivenatorFinalizer)
+	return credStore, nil
+}
 
-	return nil
+// applyREST adds the Kafka REST address and credentials when enabled with RESTAnnotation, and removes them otherwise.
+// The pool is known to have Kafka REST when enabled.
+func applyREST(application *aiven_nais_io_v1.AivenApplication, aivenUser *aiven.ServiceUser, addresses *service.ServiceAddresses, keys poolKeys, secret *v1.Secret) {
+	if application.GetAnnotations()[RESTAnnotation] != "true" {
+		keys.removeEnv(secret, KafkaRESTURI, KafkaRESTUser, KafkaRESTPassword)
+		return
+	}
+	secret.StringData = utils.MergeStringMap(secret.StringData, keys.env(map[string]string{
+		KafkaRESTURI:      addresses.KafkaREST,
+		KafkaRESTUser:     aivenUser.Username,
+		KafkaRESTPassword: aivenUser.Password,
+	}))
 }
 
-func (h KafkaHandler) provideServiceUser(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, projectName string, serviceName string, secret *v1.Secret, logger log.FieldLogger) (*aiven.ServiceUser, error) {
-	var aivenUser *aiven.ServiceUser
-	var err error
+// addressComponents are the components with addresses in the secret
+func addressComponents(application *aiven_nais_io_v1.AivenApplication, authentication string, addresses *service.ServiceAddresses) []string {
+	components := []string{service.ComponentKafka}
+	if authentication == AuthenticationSASL {
+		components = []string{service.ComponentKafkaSASL}
+	}
+	if addresses.SchemaRegistry != "" {
+		components = append(components, service.ComponentSchemaRegistry)
+	}
+	if application.GetAnnotations()[RESTAnnotation] == "true" {
+		components = append(components, service.ComponentKafkaREST)
+	}
+	return components
+}
 
-	suffix, err := createSuffix(application)
-	if err != nil {
-		err = fmt.Errorf("unable to create service user suffix: %s %w", err, utils.UnrecoverableError)
-		utils.LocalFail("CreateSuffix", application, err, logger)
-		return nil, err
+func (h KafkaHandler) clientConfigMountPath() string {
+	if h.config.ClientConfigMountPath == "" {
+		return DefaultClientConfigMountPath
 	}
+	return h.config.ClientConfigMountPath
+}
 
-	var serviceUserName string
+func authenticationMode(application *aiven_nais_io_v1.AivenApplication) (string, error) {
+	mode, ok := application.GetAnnotations()[AuthenticationAnnotation]
+	if !ok {
+		return AuthenticationMTLS, nil
+	}
+	switch mode {
+	case AuthenticationMTLS, AuthenticationSASL:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported authentication mode '%s': %w", mode, utils.UnrecoverableError)
+}
 
-	if nameFromAnnotation, ok := secret.GetAnnotations()[ServiceUserAnnotation]; ok {
-		serviceUserName = nameFromAnnotation
-	} else {
-		serviceUserName, err = kafka_nais_io_v1.ServiceUserNameWithSuffix(application.Namespace, application.Name, suffix)
+func (h KafkaHandler) provideServiceUser(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, authentication, projectName string, serviceName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	var err error
+
+	serviceUserName, adopted := secret.GetAnnotations()[keys.annotation(ServiceUserAnnotation)]
+	legacyServiceUserName := ""
+	if adopted && h.migrationDue(serviceUserName, projectName, keys, secret, logger) {
+		legacyServiceUserName = serviceUserName
+		adopted = false
+	}
+	if !adopted {
+		serviceUserName, err = h.serviceUserName(application, "")
 		if err != nil {
 			err = fmt.Errorf("unable to create service user name: %s %w", err, utils.UnrecoverableError)
 			utils.LocalFail("ServiceUserNameWithSuffix", application, err, logger)
@@ -173,11 +440,36 @@ func (h KafkaHandler) provideServiceUser(ctx context.Context, application *aiven
 		}
 	}
 
-	aivenUser, err = h.serviceuser.Get(ctx, serviceUserName, projectName, serviceName, logger)
+	aivenUser, err := h.getOrCreateServiceUser(ctx, application, serviceUserName, projectName, serviceName, adopted, secret, logger)
+	if err != nil {
+		return nil, err
+	}
+	if legacyServiceUserName != "" {
+		recordMigration(application, legacyServiceUserName, aivenUser.Username, projectName, keys, secret, logger)
+	}
+	// SASL secrets carry no certificate, so there is nothing to reissue
+	if authentication != AuthenticationSASL && h.certificateExpiring(aivenUser, logger) {
+		return h.reissueServiceUser(ctx, application, aivenUser.Username, projectName, serviceName, keys, secret, logger)
+	}
+	return aivenUser, nil
+}
+
+func (h KafkaHandler) getOrCreateServiceUser(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, serviceUserName, projectName, serviceName string, adopted bool, secret *v1.Secret, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	aivenUser, err := h.serviceuser.Get(ctx, serviceUserName, projectName, serviceName, logger)
+	if err == nil && !adopted {
+		owner, err := h.ownerOf(ctx, serviceUserName, projectName, secret)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check ownership of service user %s: %w", serviceUserName, err)
+		}
+		if owner != "" {
+			err = fmt.Errorf("service user %s already belongs to secret %s: %w", serviceUserName, owner, utils.UnrecoverableError)
+			utils.LocalFail("ServiceUserCollision", application, err, logger)
+			return nil, err
+		}
+	}
 	if err == nil {
 		return aivenUser, nil
-	}
-	if !aiven.IsNotFound(err) {
+	} else if !aiven.IsNotFound(err) {
 		return nil, utils.AivenFail("GetServiceUser", application, err, false, logger)
 	}
 
@@ -188,44 +480,78 @@ func (h KafkaHandler) provideServiceUser(ctx context.Context, application *aiven
 	return aivenUser, nil
 }
 
-func createSuffix(application *aiven_nais_io_v1.AivenApplication) (string, error) {
-	hasher := crc32.NewIEEE()
-	basename := fmt.Sprintf("%d%s", application.Generation, clusterName)
-	_, err := hasher.Write([]byte(basename))
-	if err != nil {
-		return "", err
-	}
-	bytes := make([]byte, 0, 4)
-	suffix := base64.RawURLEncoding.EncodeToString(hasher.Sum(bytes))
-	return suffix[:3], nil
-}
-
 func (h KafkaHandler) Cleanup(ctx context.Context, secret *v1.Secret, logger *log.Entry) error {
 	annotations := secret.GetAnnotations()
-	if serviceUserName, okServiceUser := annotations[ServiceUserAnnotation]; okServiceUser {
-		if projectName, okPool := annotations[PoolAnnotation]; okPool {
-			serviceName, err := h.nameResolver.ResolveKafkaServiceName(projectName)
-			if err != nil {
-				return err
-			}
-			logger = logger.WithFields(log.Fields{
-				"pool":    projectName,
-				"service": serviceName,
-			})
-			err = h.serviceuser.Delete(ctx, serviceUserName, projectName, serviceName, logger)
-			if err != nil {
-				if aiven.IsNotFound(err) {
-					logger.Infof("Service user %s does not exist", serviceUserName)
-					return nil
-				}
-				return err
-			}
-			logger.Infof("Deleted service user %s", serviceUserName)
-		} else {
+	for key, serviceUserName := range annotations {
+		prefix, ok := poolPrefixFor(key)
+		if !ok {
+			continue
+		}
+		projectName, okPool := annotations[prefix+PoolAnnotation]
+		if !okPool {
 			return fmt.Errorf("missing pool annotation on secret %s in namespace %s, unable to delete service user %s",
 				secret.GetName(), secret.GetNamespace(), serviceUserName)
 		}
+		resources := serviceUserResourcesFor(annotations, prefix)
+		err := h.deleteServiceUser(ctx, serviceUserName, projectName, resources, logger)
+		if err != nil {
+			return err
+		}
+		for _, annotation := range []string{LegacyServiceUserAnnotation, ReplacedServiceUserAnnotation} {
+			if previousServiceUserName, ok := annotations[prefix+annotation]; ok {
+				err = h.deleteServiceUser(ctx, previousServiceUserName, projectName, resources, logger)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// serviceUserResources are the resources tied to a service user that must be removed along with it
+type serviceUserResources struct {
+	quota              bool
+	schemaRegistryACLs bool
+}
+
+// serviceUserResourcesFor finds the resources recorded on the secret for the pool with the given annotation prefix
+func serviceUserResourcesFor(annotations map[string]string, prefix string) serviceUserResources {
+	_, hasQuota := annotations[prefix+AppliedQuotaAnnotation]
+	_, hasSchemaACLs := annotations[prefix+AppliedSchemaRegistryACLAnnotation]
+	return serviceUserResources{quota: hasQuota, schemaRegistryACLs: hasSchemaACLs}
+}
+
+func (h KafkaHandler) deleteServiceUser(ctx context.Context, serviceUserName, projectName string, resources serviceUserResources, logger log.FieldLogger) error {
+	serviceName, err := h.nameResolver.ResolveKafkaServiceName(projectName)
+	if err != nil {
+		return err
+	}
+	logger = logger.WithFields(log.Fields{
+		"pool":    projectName,
+		"service": serviceName,
+	})
+	if resources.quota {
+		err = h.deleteQuota(ctx, serviceUserName, projectName, serviceName, logger)
+		if err != nil {
+			return err
+		}
 	}
+	if resources.schemaRegistryACLs {
+		err = h.deleteSchemaRegistryACLs(ctx, serviceUserName, projectName, serviceName, logger)
+		if err != nil {
+			return err
+		}
+	}
+	err = h.serviceuser.Delete(ctx, serviceUserName, projectName, serviceName, logger)
+	if err != nil {
+		if aiven.IsNotFound(err) {
+			logger.Infof("Service user %s does not exist", serviceUserName)
+			return nil
+		}
+		return err
+	}
+	logger.Infof("Deleted service user %s", serviceUserName)
 	return nil
 }
 
@@ -253,7 +579,3 @@ func (h *KafkaHandler) countUsers(ctx context.Context, logger *log.Entry) {
 		}
 	}
 }
-
-func init() {
-	clusterName = os.Getenv("NAIS_CLUSTER_NAME")
-}
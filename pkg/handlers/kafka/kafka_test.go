@@ -5,23 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"github.com/nais/aivenator/pkg/aiven/project"
+	"github.com/nais/aivenator/pkg/aiven/quota"
+	"github.com/nais/aivenator/pkg/aiven/schemaregistry"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
 	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aiven/aiven-go-client/v2"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	kafka_nais_io_v1 "github.com/nais/liberator/pkg/apis/kafka.nais.io/v1"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/certificate"
+	"github.com/nais/aivenator/pkg/certificate/certtest"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	liberator_service "github.com/nais/liberator/pkg/aiven/service"
 )
@@ -30,9 +37,11 @@ const (
 	serviceUserName = "service-user-name"
 	credStoreSecret = "my-secret"
 	serviceURI      = "http://example.com"
+	saslURI         = "example.com:23456"
 	ca              = "my-ca"
 	pool            = "my-testing-pool"
 	invalidPool     = "not-my-testing-pool"
+	otherPool       = "nav-integration-test"
 )
 
 const (
@@ -57,6 +66,8 @@ type KafkaHandlerTestSuite struct {
 
 	logger             *log.Entry
 	mockProjects       *project.MockProjectManager
+	mockQuotas         *quota.MockQuotaManager
+	mockSchemaRegistry *schemaregistry.MockACLManager
 	mockServiceUsers   *serviceuser.MockServiceUserManager
 	mockServices       *service.MockServiceManager
 	mockGenerator      *certificate.MockGenerator
@@ -104,7 +115,7 @@ func (suite *KafkaHandlerTestSuite) addDefaultMocks(enabled map[int]struct{}) {
 			})
 	}
 	if _, ok := enabled[GeneratorMakeCredStores]; ok {
-		suite.mockGenerator.Mock.On("MakeCredStores", mock.Anything, mock.Anything, mock.Anything).
+		suite.mockGenerator.Mock.On("MakeCredStores", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(&certificate.CredStoreData{
 				Keystore:   []byte("my-keystore"),
 				Truststore: []byte("my-truststore"),
@@ -117,16 +128,21 @@ func (suite *KafkaHandlerTestSuite) SetupTest() {
 	suite.mockServiceUsers = &serviceuser.MockServiceUserManager{}
 	suite.mockServices = &service.MockServiceManager{}
 	suite.mockProjects = &project.MockProjectManager{}
+	suite.mockQuotas = &quota.MockQuotaManager{}
+	suite.mockSchemaRegistry = &schemaregistry.MockACLManager{}
 	suite.mockGenerator = &certificate.MockGenerator{}
 	suite.mockNameResolver = liberator_service.NewMockNameResolver(suite.T())
 	suite.mockNameResolver.On("ResolveKafkaServiceName", mock.Anything).Maybe().Return("kafka", nil)
 	suite.kafkaHandler = KafkaHandler{
-		project:      suite.mockProjects,
-		serviceuser:  suite.mockServiceUsers,
-		service:      suite.mockServices,
-		generator:    suite.mockGenerator,
-		nameResolver: suite.mockNameResolver,
-		projects:     []string{"nav-integration-test", "my-testing-pool"},
+		project:        suite.mockProjects,
+		quota:          suite.mockQuotas,
+		schemaRegistry: suite.mockSchemaRegistry,
+		serviceuser:    suite.mockServiceUsers,
+		service:        suite.mockServices,
+		generator:      suite.mockGenerator,
+		nameResolver:   suite.mockNameResolver,
+		authorizer:     policy.AllowAll{},
+		projects:       []string{"nav-integration-test", "my-testing-pool"},
 	}
 	suite.applicationBuilder = aiven_nais_io_v1.NewAivenApplicationBuilder("test-app", "test-ns")
 	suite.ctx, suite.cancel = context.WithTimeout(context.Background(), 5*time.Second)
@@ -176,17 +192,686 @@ func (suite *KafkaHandlerTestSuite) TestCleanupServiceUserAlreadyGone() {
 	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
 }
 
+func (suite *KafkaHandlerTestSuite) TestCleanupAdditionalPools() {
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:   serviceUserName,
+		PoolAnnotation:          pool,
+		CAFingerprintAnnotation: project.Fingerprint(ca),
+		"nav-integration-test.kafka.aiven.nais.io/serviceUser":   serviceUserName,
+		"nav-integration-test.kafka.aiven.nais.io/pool":          otherPool,
+		"nav-integration-test.kafka.aiven.nais.io/caFingerprint": project.Fingerprint(ca),
+	})
+	suite.mockServiceUsers.On("Delete", mock.Anything, serviceUserName, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, otherPool, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCleanupMissingPool() {
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		"nav-integration-test.kafka.aiven.nais.io/serviceUser": serviceUserName,
+	})
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.Error(err)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCleanupQuota() {
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:  serviceUserName,
+		PoolAnnotation:         pool,
+		AppliedQuotaAnnotation: "consumer_byte_rate=1024,producer_byte_rate=0,request_percentage=0",
+	})
+	suite.mockQuotas.On("Delete", mock.Anything, serviceUserName, pool, mock.Anything).
+		Return(nil)
+	suite.mockServiceUsers.On("Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything).
+		Return(nil)
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockQuotas.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCleanupSchemaRegistryACLs() {
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:              serviceUserName,
+		PoolAnnotation:                     pool,
+		AppliedSchemaRegistryACLAnnotation: "schema_registry_read Subject:*",
+	})
+	suite.mockSchemaRegistry.On("List", mock.Anything, pool, mock.Anything).
+		Return([]*aiven.KafkaSchemaRegistryACL{
+			{ID: "acl-mine", Permission: schemaregistry.PermissionRead, Resource: "Subject:*", Username: serviceUserName},
+			{ID: "acl-other", Permission: schemaregistry.PermissionRead, Resource: "Subject:*", Username: "other-user"},
+		}, nil)
+	suite.mockSchemaRegistry.On("Delete", mock.Anything, pool, mock.Anything, "acl-mine").
+		Return(nil)
+	suite.mockServiceUsers.On("Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything).
+		Return(nil)
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockSchemaRegistry.AssertNumberOfCalls(suite.T(), "Delete", 1)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+}
+
 func (suite *KafkaHandlerTestSuite) TestNoKafka() {
 	application := suite.applicationBuilder.Build()
 	secret := &v1.Secret{}
 	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
 
 	suite.NoError(err)
-	suite.Equal(&v1.Secret{}, secret)
+	suite.Equal(&v1.Secret{}, secret)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaOk() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	expected := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation:   serviceUserName,
+				PoolAnnotation:          pool,
+				CAFingerprintAnnotation: project.Fingerprint(ca),
+			},
+			Finalizers: []string{constants.AivenatorFinalizer},
+		},
+		// Check these individually
+		Data:       secret.Data,
+		StringData: secret.StringData,
+	}
+	suite.Equal(expected, secret)
+
+	suite.ElementsMatch(utils.KeysFromStringMap(secret.StringData), []string{
+		KafkaCA, KafkaPrivateKey, KafkaCredStorePassword, KafkaSchemaRegistry, KafkaSchemaUser, KafkaSchemaPassword,
+		KafkaBrokers, KafkaSecretUpdated, KafkaCertificate,
+	})
+	suite.ElementsMatch(utils.KeysFromByteMap(secret.Data), []string{KafkaKeystore, KafkaTruststore})
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaAddressRouteRecorded() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI: serviceURI,
+			KafkaSASL:  saslURI,
+			Routes: map[string]string{
+				service.ComponentKafka:     "private",
+				service.ComponentKafkaSASL: "dynamic",
+			},
+		}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal("private", secret.GetAnnotations()[AddressRouteAnnotation])
+	suite.Equal(serviceURI, secret.StringData[KafkaBrokers])
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSASLOk() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI: serviceURI,
+			KafkaSASL:  saslURI,
+		}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AuthenticationAnnotation, AuthenticationSASL).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockGenerator.AssertNotCalled(suite.T(), "MakeCredStores", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.ElementsMatch(utils.KeysFromStringMap(secret.StringData), []string{
+		KafkaCA, KafkaSchemaRegistry, KafkaSchemaUser, KafkaSchemaPassword, KafkaSecretUpdated,
+		KafkaSASLBrokers, KafkaSASLUsername, KafkaSASLPassword, KafkaSASLMechanism,
+	})
+	suite.Equal(saslURI, secret.StringData[KafkaSASLBrokers])
+	suite.Equal(serviceUserName, secret.StringData[KafkaSASLUsername])
+	suite.Equal("SCRAM-SHA-512", secret.StringData[KafkaSASLMechanism])
+	suite.Empty(secret.Data)
+	suite.Contains(secret.GetFinalizers(), constants.AivenatorFinalizer)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSASLNotEnabled() {
+	// Only the addresses are looked up, any call provisioning resources in Aiven fails the test
+	suite.addDefaultMocks(enabled(ServicesGetAddresses))
+	suite.kafkaHandler.config.Quota = quota.Quota{ConsumerByteRate: 1024}
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AuthenticationAnnotation, AuthenticationSASL).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+	suite.assertNothingProvisioned()
+}
+
+// assertNothingProvisioned checks that no service user, quota or schema registry ACL was created
+func (suite *KafkaHandlerTestSuite) assertNothingProvisioned() {
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockQuotas.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockSchemaRegistry.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaREST() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI: serviceURI,
+			KafkaREST:  "https://kafka-rest.example.com:26489",
+		}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(RESTAnnotation, "true").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal("https://kafka-rest.example.com:26489", secret.StringData[KafkaRESTURI])
+	suite.Equal(serviceUserName, secret.StringData[KafkaRESTUser])
+	suite.Contains(secret.StringData, KafkaRESTPassword)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaRESTNotEnabled() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses))
+	suite.kafkaHandler.config.Quota = quota.Quota{ConsumerByteRate: 1024}
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(RESTAnnotation, "true").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+	suite.assertNothingProvisioned()
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaRESTRemoved() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{
+		Data: map[string][]byte{
+			KafkaRESTURI:      []byte("https://kafka-rest.example.com:26489"),
+			KafkaRESTUser:     []byte(serviceUserName),
+			KafkaRESTPassword: []byte("password"),
+		},
+	}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.NotContains(secret.Data, KafkaRESTURI)
+	suite.NotContains(secret.Data, KafkaRESTUser)
+	suite.NotContains(secret.Data, KafkaRESTPassword)
+}
+
+func (suite *KafkaHandlerTestSuite) TestInvalidAuthentication() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AuthenticationAnnotation, "kerberos").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaAdditionalPools() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AdditionalPoolsAnnotation, fmt.Sprintf("%s, %s", otherPool, pool)).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockServiceUsers.AssertNumberOfCalls(suite.T(), "Create", 2)
+	suite.Equal(map[string]string{
+		ServiceUserAnnotation:   serviceUserName,
+		PoolAnnotation:          pool,
+		CAFingerprintAnnotation: project.Fingerprint(ca),
+		"nav-integration-test.kafka.aiven.nais.io/serviceUser":   serviceUserName,
+		"nav-integration-test.kafka.aiven.nais.io/pool":          otherPool,
+		"nav-integration-test.kafka.aiven.nais.io/caFingerprint": project.Fingerprint(ca),
+	}, secret.GetAnnotations())
+	suite.Empty(validation.ValidateAnnotations(secret.GetAnnotations(), field.NewPath("metadata.annotations")))
+	suite.Equal(serviceURI, secret.StringData[KafkaBrokers])
+	suite.Equal(serviceURI, secret.StringData["NAV_INTEGRATION_TEST_KAFKA_BROKERS"])
+	suite.Contains(secret.StringData, "NAV_INTEGRATION_TEST_KAFKA_CREDSTORE_PASSWORD")
+	suite.ElementsMatch(utils.KeysFromByteMap(secret.Data), []string{
+		KafkaKeystore, KafkaTruststore,
+		"nav-integration-test.client.keystore.p12", "nav-integration-test.client.truststore.jks",
+	})
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaAdditionalPoolNotAllowed() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AdditionalPoolsAnnotation, invalidPool).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaStoreFormats() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, ServiceUsersGetNotFound))
+	expectedFormats := certificate.Formats{Keystore: certificate.JKS, Truststore: certificate.PKCS12, PEM: true}
+	suite.mockGenerator.On("MakeCredStores", mock.Anything, mock.Anything, mock.Anything, expectedFormats).
+		Return(&certificate.CredStoreData{
+			Keystore:         []byte("my-keystore"),
+			Truststore:       []byte("my-truststore"),
+			Secret:           credStoreSecret,
+			KeyBundle:        []byte("my-bundle"),
+			CertificateChain: []byte("my-chain"),
+		}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	application.SetAnnotations(map[string]string{
+		KeystoreFormatAnnotation:   "jks",
+		TruststoreFormatAnnotation: "pkcs12",
+		PEMBundlesAnnotation:       "true",
+		ClientConfigAnnotation:     "true",
+	})
+	secret := &v1.Secret{
+		Data: map[string][]byte{
+			KafkaKeystore:   []byte("old-keystore"),
+			KafkaTruststore: []byte("old-truststore"),
+		},
+	}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.ElementsMatch(utils.KeysFromByteMap(secret.Data), []string{
+		KafkaJKSKeystore, KafkaPKCS12Truststore, KafkaKeyBundle, KafkaCertificateChain,
+		KafkaClientProperties, KafkaLibrdkafkaConfig, KafkaKCatConfig,
+	})
+	properties := string(secret.Data[KafkaClientProperties])
+	suite.Contains(properties, "ssl.keystore.type=JKS\n")
+	suite.Contains(properties, "ssl.keystore.location=/var/run/secrets/nais.io/kafka/client.keystore.jks\n")
+	suite.Contains(properties, "ssl.truststore.type=PKCS12\n")
+	suite.Contains(properties, "ssl.truststore.location=/var/run/secrets/nais.io/kafka/client.truststore.p12\n")
+}
+
+func (suite *KafkaHandlerTestSuite) TestInvalidStoreFormat() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(KeystoreFormatAnnotation, "bks").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.ErrorIs(err, utils.UnrecoverableError)
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaClientConfig() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.kafkaHandler.config.ClientConfigMountPath = "/mnt/kafka"
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	application.SetAnnotations(map[string]string{
+		ClientConfigAnnotation:    "true",
+		AdditionalPoolsAnnotation: otherPool,
+	})
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Contains(secret.Data, KafkaLibrdkafkaConfig)
+	suite.Contains(secret.Data, KafkaKCatConfig)
+	suite.Contains(secret.Data, "nav-integration-test.client.properties")
+
+	properties := string(secret.Data[KafkaClientProperties])
+	suite.Contains(properties, "bootstrap.servers=http://example.com\n")
+	suite.Contains(properties, "security.protocol=SSL\n")
+	suite.Contains(properties, "ssl.keystore.location=/mnt/kafka/client.keystore.p12\n")
+	suite.Contains(properties, "ssl.keystore.password=my-secret\n")
+	suite.Contains(properties, "ssl.truststore.location=/mnt/kafka/client.truststore.jks\n")
+
+	otherProperties := string(secret.Data["nav-integration-test.client.properties"])
+	suite.Contains(otherProperties, "ssl.keystore.location=/mnt/kafka/nav-integration-test.client.keystore.p12\n")
+
+	librdkafka := string(secret.Data[KafkaLibrdkafkaConfig])
+	suite.Contains(librdkafka, "ssl.ca.location=/mnt/kafka/KAFKA_CA\n")
+	suite.Contains(librdkafka, "ssl.certificate.location=/mnt/kafka/KAFKA_CERTIFICATE\n")
+	suite.Contains(librdkafka, "ssl.key.location=/mnt/kafka/KAFKA_PRIVATE_KEY\n")
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSASLClientConfig() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI:     serviceURI,
+			KafkaSASL:      saslURI,
+			SchemaRegistry: "https://schema-registry.example.com",
+		}, nil)
+	suite.mockSchemaRegistry.On("List", mock.Anything, pool, mock.Anything).
+		Return([]*aiven.KafkaSchemaRegistryACL{}, nil)
+	suite.mockSchemaRegistry.On("Create", mock.Anything, pool, mock.Anything, mock.Anything).
+		Return(nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	application.SetAnnotations(map[string]string{
+		AuthenticationAnnotation: AuthenticationSASL,
+		ClientConfigAnnotation:   "true",
+	})
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	properties := string(secret.Data[KafkaClientProperties])
+	suite.Contains(properties, "bootstrap.servers=example.com:23456\n")
+	suite.Contains(properties, "security.protocol=SASL_SSL\n")
+	suite.Contains(properties, "ssl.truststore.location=/var/run/secrets/nais.io/kafka/KAFKA_CA\n")
+	suite.Contains(properties, "schema.registry.url=https://schema-registry.example.com\n")
+	suite.Contains(properties, fmt.Sprintf("basic.auth.user.info=%s:\n", serviceUserName))
+	suite.Contains(string(secret.Data[KafkaLibrdkafkaConfig]), "security.protocol=sasl_ssl\n")
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaClientConfigRemoved() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{
+		Data: map[string][]byte{
+			KafkaClientProperties: []byte("sasl.jaas.config=secret"),
+			KafkaLibrdkafkaConfig: []byte("sasl.password=secret"),
+			KafkaKCatConfig:       []byte("sasl.password=secret"),
+		},
+	}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.NotContains(secret.Data, KafkaClientProperties)
+	suite.NotContains(secret.Data, KafkaLibrdkafkaConfig)
+	suite.NotContains(secret.Data, KafkaKCatConfig)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSwitchToSASLRemovesCertificate() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI: serviceURI,
+			KafkaSASL:  saslURI,
+		}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(AuthenticationAnnotation, AuthenticationSASL).
+		Build()
+	secret := &v1.Secret{
+		Data: map[string][]byte{
+			KafkaCertificate:       []byte("certificate"),
+			KafkaPrivateKey:        []byte("private-key"),
+			KafkaBrokers:           []byte(serviceURI),
+			KafkaCredStorePassword: []byte(credStoreSecret),
+			KafkaKeystore:          []byte("my-keystore"),
+			KafkaTruststore:        []byte("my-truststore"),
+		},
+	}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Empty(secret.Data)
+	suite.Contains(secret.StringData, KafkaSASLPassword)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSwitchToMTLSRemovesSASL() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{
+		Data: map[string][]byte{
+			KafkaSASLBrokers:   []byte(saslURI),
+			KafkaSASLUsername:  []byte(serviceUserName),
+			KafkaSASLPassword:  []byte("password"),
+			KafkaSASLMechanism: []byte("SCRAM-SHA-512"),
+		},
+	}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.NotContains(secret.Data, KafkaSASLBrokers)
+	suite.NotContains(secret.Data, KafkaSASLUsername)
+	suite.NotContains(secret.Data, KafkaSASLPassword)
+	suite.NotContains(secret.Data, KafkaSASLMechanism)
+	suite.Contains(secret.StringData, KafkaCertificate)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaQuotaApplied() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.kafkaHandler.config.Quota = quota.Quota{ConsumerByteRate: 1024, ProducerByteRate: 2048}
+	expected := quota.Quota{ConsumerByteRate: 1024, ProducerByteRate: 4096, RequestPercentage: 25}
+	suite.mockQuotas.On("Get", mock.Anything, serviceUserName, pool, mock.Anything).
+		Return(nil, aiven.Error{Message: "Not Found", Status: 404})
+	suite.mockQuotas.On("Set", mock.Anything, serviceUserName, pool, mock.Anything, expected).
+		Return(nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(QuotaAnnotation, "producerByteRate=4096, requestPercentage=25").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockQuotas.AssertCalled(suite.T(), "Set", mock.Anything, serviceUserName, pool, mock.Anything, expected)
+	suite.Equal(expected.String(), secret.GetAnnotations()[AppliedQuotaAnnotation])
+	condition := application.Status.GetConditionOfType(KafkaQuotaCondition)
+	suite.Require().NotNil(condition)
+	suite.Equal(v1.ConditionTrue, condition.Status)
+	suite.Equal(fmt.Sprintf("%s: %s", pool, expected), condition.Message)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaQuotaUnchanged() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.kafkaHandler.config.Quota = quota.Quota{ConsumerByteRate: 1024}
+	suite.mockQuotas.On("Get", mock.Anything, serviceUserName, pool, mock.Anything).
+		Return(&quota.Quota{ConsumerByteRate: 1024}, nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockQuotas.AssertNotCalled(suite.T(), "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.Contains(secret.GetAnnotations(), AppliedQuotaAnnotation)
+}
+
+func (suite *KafkaHandlerTestSuite) TestKafkaQuotaRemoved() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores, ServiceUsersGet))
+	suite.mockQuotas.On("Delete", mock.Anything, serviceUserName, pool, mock.Anything).
+		Return(nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	application.Status.AddCondition(aiven_nais_io_v1.AivenApplicationCondition{Type: KafkaQuotaCondition, Status: v1.ConditionTrue})
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:  serviceUserName,
+		AppliedQuotaAnnotation: "consumer_byte_rate=1024,producer_byte_rate=0,request_percentage=0",
+	})
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockQuotas.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything)
+	suite.NotContains(secret.GetAnnotations(), AppliedQuotaAnnotation)
+	suite.Nil(application.Status.GetConditionOfType(KafkaQuotaCondition))
 }
 
-func (suite *KafkaHandlerTestSuite) TestKafkaOk() {
-	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+func (suite *KafkaHandlerTestSuite) TestInvalidQuota() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(QuotaAnnotation, "fetchRate=10").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.ErrorIs(err, utils.UnrecoverableError)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestSecretExists() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation: serviceUserName,
+			},
+		},
+	}
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores, ServiceUsersGet))
+
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Empty(validation.ValidateAnnotations(secret.GetAnnotations(), field.NewPath("metadata.annotations")))
+	suite.Equal(secret.GetAnnotations()[ServiceUserAnnotation], serviceUserName)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCertificateExpiryRecorded() {
+	notAfter := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second).UTC()
+	suite.kafkaHandler.config.CertificateReissueWindow = 30 * 24 * time.Hour
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores))
+	suite.mockServiceUsers.On("Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   serviceUserName,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, notAfter).Certificate,
+		}, nil)
 	application := suite.applicationBuilder.
 		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
 			Kafka: &aiven_nais_io_v1.KafkaSpec{
@@ -195,38 +880,81 @@ func (suite *KafkaHandlerTestSuite) TestKafkaOk() {
 		}).
 		Build()
 	secret := &v1.Secret{}
+
 	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
 
 	suite.NoError(err)
-	expected := &v1.Secret{
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(notAfter.Format(time.RFC3339), secret.GetAnnotations()[CertificateExpiryAnnotation])
+	suite.Equal(notAfter.Add(-30*24*time.Hour).Format(time.RFC3339), secret.GetAnnotations()[constants.AivenatorRenewAfterAnnotation])
+	expiry, ok := EarliestCertificateExpiry(secret.GetAnnotations())
+	suite.True(ok)
+	suite.True(notAfter.Equal(expiry))
+}
+
+func (suite *KafkaHandlerTestSuite) TestCertificateExpiringIsReissued() {
+	suite.kafkaHandler.config.CertificateReissueWindow = 30 * 24 * time.Hour
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	replacement, err := suite.kafkaHandler.serviceUserName(&application, serviceUserName)
+	suite.Require().NoError(err)
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores))
+	suite.mockServiceUsers.On("Get", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   serviceUserName,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, time.Now().Add(7*24*time.Hour)).Certificate,
+		}, nil)
+	suite.mockServiceUsers.On("Get", mock.Anything, replacement, pool, mock.Anything, mock.Anything).
+		Return(nil, aiven.Error{Status: 404})
+	suite.mockServiceUsers.On("Create", mock.Anything, replacement, pool, mock.Anything, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   replacement,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, time.Now().Add(365*24*time.Hour)).Certificate,
+		}, nil)
+	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Annotations: map[string]string{
 				ServiceUserAnnotation: serviceUserName,
-				PoolAnnotation:        pool,
 			},
-			Finalizers: []string{constants.AivenatorFinalizer},
 		},
-		// Check these individually
-		Data:       secret.Data,
-		StringData: secret.StringData,
 	}
-	suite.Equal(expected, secret)
 
-	suite.ElementsMatch(utils.KeysFromStringMap(secret.StringData), []string{
-		KafkaCA, KafkaPrivateKey, KafkaCredStorePassword, KafkaSchemaRegistry, KafkaSchemaUser, KafkaSchemaPassword,
-		KafkaBrokers, KafkaSecretUpdated, KafkaCertificate,
-	})
-	suite.ElementsMatch(utils.KeysFromByteMap(secret.Data), []string{KafkaKeystore, KafkaTruststore})
+	err = suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.NotEqual(serviceUserName, replacement)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(replacement, secret.GetAnnotations()[ServiceUserAnnotation])
+	suite.Equal(serviceUserName, secret.GetAnnotations()[ReplacedServiceUserAnnotation])
+	suite.Contains(secret.GetAnnotations(), constants.AivenatorRenewAfterAnnotation)
 }
 
-func (suite *KafkaHandlerTestSuite) TestSecretExists() {
+func (suite *KafkaHandlerTestSuite) TestCertificateExpiringNotReissuedForSASL() {
+	suite.kafkaHandler.config.CertificateReissueWindow = 30 * 24 * time.Hour
 	application := suite.applicationBuilder.
 		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
 			Kafka: &aiven_nais_io_v1.KafkaSpec{
 				Pool: pool,
 			},
 		}).
+		WithAnnotation(AuthenticationAnnotation, AuthenticationSASL).
 		Build()
+	suite.addDefaultMocks(enabled(ProjectGetCA))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI: serviceURI,
+			KafkaSASL:  saslURI,
+		}, nil)
+	suite.mockServiceUsers.On("Get", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   serviceUserName,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, time.Now().Add(7*24*time.Hour)).Certificate,
+		}, nil)
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Annotations: map[string]string{
@@ -234,13 +962,58 @@ func (suite *KafkaHandlerTestSuite) TestSecretExists() {
 			},
 		},
 	}
-	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores, ServiceUsersGet))
 
 	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
 
 	suite.NoError(err)
-	suite.Empty(validation.ValidateAnnotations(secret.GetAnnotations(), field.NewPath("metadata.annotations")))
-	suite.Equal(secret.GetAnnotations()[ServiceUserAnnotation], serviceUserName)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(serviceUserName, secret.GetAnnotations()[ServiceUserAnnotation])
+	suite.NotContains(secret.GetAnnotations(), ReplacedServiceUserAnnotation)
+	suite.NotContains(secret.GetAnnotations(), constants.AivenatorRenewAfterAnnotation)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCertificateReissueDeletesPreviouslyReplaced() {
+	const previouslyReplaced = "previously-replaced"
+	suite.kafkaHandler.config.CertificateReissueWindow = 30 * 24 * time.Hour
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	replacement, err := suite.kafkaHandler.serviceUserName(&application, serviceUserName)
+	suite.Require().NoError(err)
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores))
+	suite.mockServiceUsers.On("Get", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   serviceUserName,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, time.Now().Add(7*24*time.Hour)).Certificate,
+		}, nil)
+	suite.mockServiceUsers.On("Get", mock.Anything, replacement, pool, mock.Anything, mock.Anything).
+		Return(nil, aiven.Error{Status: 404})
+	suite.mockServiceUsers.On("Create", mock.Anything, replacement, pool, mock.Anything, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{
+			Username:   replacement,
+			AccessCert: certtest.SelfSigned(suite.T(), serviceUserName, time.Now().Add(365*24*time.Hour)).Certificate,
+		}, nil)
+	suite.mockServiceUsers.On("Delete", mock.Anything, previouslyReplaced, pool, mock.Anything, mock.Anything).
+		Return(nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation:         serviceUserName,
+				ReplacedServiceUserAnnotation: previouslyReplaced,
+			},
+		},
+	}
+
+	err = suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, previouslyReplaced, pool, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+	suite.Equal(serviceUserName, secret.GetAnnotations()[ReplacedServiceUserAnnotation])
 }
 
 func (suite *KafkaHandlerTestSuite) TestServiceGetFailed() {
@@ -335,8 +1108,9 @@ func (suite *KafkaHandlerTestSuite) TestServiceUserNotFound() {
 	expected := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Annotations: map[string]string{
-				ServiceUserAnnotation: serviceUserName,
-				PoolAnnotation:        pool,
+				ServiceUserAnnotation:   serviceUserName,
+				PoolAnnotation:          pool,
+				CAFingerprintAnnotation: project.Fingerprint(ca),
 			},
 			Finalizers: []string{constants.AivenatorFinalizer},
 		},
@@ -372,8 +1146,9 @@ func (suite *KafkaHandlerTestSuite) TestServiceUserCollision() {
 	expected := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Annotations: map[string]string{
-				ServiceUserAnnotation: serviceUserName,
-				PoolAnnotation:        pool,
+				ServiceUserAnnotation:   serviceUserName,
+				PoolAnnotation:          pool,
+				CAFingerprintAnnotation: project.Fingerprint(ca),
 			},
 			Finalizers: []string{constants.AivenatorFinalizer},
 		},
@@ -410,7 +1185,7 @@ func (suite *KafkaHandlerTestSuite) TestGeneratorMakeCredStoresFailed() {
 		Build()
 	secret := &v1.Secret{}
 	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, ServiceUsersCreate, ServiceUsersGetNotFound))
-	suite.mockGenerator.On("MakeCredStores", mock.Anything, mock.Anything, mock.Anything).
+	suite.mockGenerator.On("MakeCredStores", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(nil, fmt.Errorf("local-fail"))
 
 	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
@@ -423,3 +1198,257 @@ func TestKafkaHandler(t *testing.T) {
 	kafkaTestSuite := new(KafkaHandlerTestSuite)
 	suite.Run(t, kafkaTestSuite)
 }
+
+func (suite *KafkaHandlerTestSuite) TestKafkaSchemaRegistryACLs() {
+	suite.addDefaultMocks(enabled(ProjectGetCA, ServiceUsersCreate, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.ServiceAddresses{
+			ServiceURI:     serviceURI,
+			SchemaRegistry: "https://schema-registry.example.com",
+		}, nil)
+	suite.mockSchemaRegistry.On("List", mock.Anything, pool, mock.Anything).
+		Return([]*aiven.KafkaSchemaRegistryACL{
+			{ID: "acl-read", Permission: schemaregistry.PermissionRead, Resource: "Subject:*", Username: serviceUserName},
+			{ID: "acl-stale", Permission: schemaregistry.PermissionWrite, Resource: "Subject:myteam.old*", Username: serviceUserName},
+			{ID: "acl-other", Permission: schemaregistry.PermissionWrite, Resource: "Subject:myteam.orders*", Username: "other-user"},
+		}, nil)
+	expected := aiven.CreateKafkaSchemaRegistryACLRequest{
+		Permission: schemaregistry.PermissionWrite,
+		Resource:   "Subject:myteam.orders*",
+		Username:   serviceUserName,
+	}
+	suite.mockSchemaRegistry.On("Create", mock.Anything, pool, mock.Anything, expected).
+		Return(nil)
+	suite.mockSchemaRegistry.On("Delete", mock.Anything, pool, mock.Anything, "acl-stale").
+		Return(nil)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(SchemaRegistryWriteAnnotation, "myteam.orders").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockSchemaRegistry.AssertNumberOfCalls(suite.T(), "Create", 1)
+	suite.mockSchemaRegistry.AssertNumberOfCalls(suite.T(), "Delete", 1)
+	suite.Equal("schema_registry_read Subject:*, schema_registry_write Subject:myteam.orders*", secret.GetAnnotations()[AppliedSchemaRegistryACLAnnotation])
+}
+
+func (suite *KafkaHandlerTestSuite) TestInvalidSchemaRegistryPrefix() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		WithAnnotation(SchemaRegistryWriteAnnotation, "myteam.*").
+		Build()
+	secret := &v1.Secret{}
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+}
+
+func (suite *KafkaHandlerTestSuite) TestServiceUserNameSuffix() {
+	suite.kafkaHandler.config.ClusterName = "dev-gcp"
+	application := suite.applicationBuilder.Build()
+	application.Generation = 2
+
+	name, err := suite.kafkaHandler.serviceUserName(&application, "")
+	suite.NoError(err)
+
+	suite.kafkaHandler.config.ClusterName = "prod-gcp"
+	otherCluster, err := suite.kafkaHandler.serviceUserName(&application, "")
+	suite.NoError(err)
+	suite.NotEqual(name, otherCluster)
+
+	application.Generation = 3
+	otherGeneration, err := suite.kafkaHandler.serviceUserName(&application, "")
+	suite.NoError(err)
+	suite.NotEqual(otherCluster, otherGeneration)
+}
+
+func (suite *KafkaHandlerTestSuite) TestServiceUserNameLongSuffix() {
+	suite.kafkaHandler.config.ClusterName = "dev-gcp"
+	suite.kafkaHandler.config.ServiceUserSuffixLength = MaxServiceUserSuffixLength
+
+	suite.Run("short names", func() {
+		application := aiven_nais_io_v1.NewAivenApplicationBuilder("app", "team").Build()
+
+		name, err := suite.kafkaHandler.serviceUserName(&application, "")
+
+		suite.NoError(err)
+		suite.True(strings.HasSuffix(name, suite.kafkaHandler.createSuffix(&application, "")))
+		suite.matchesKafkaratorACL(name, &application)
+	})
+
+	suite.Run("long names", func() {
+		application := aiven_nais_io_v1.NewAivenApplicationBuilder("an-application-with-a-very-long-name-indeed", "a-team-with-a-long-name").Build()
+
+		name, err := suite.kafkaHandler.serviceUserName(&application, "")
+
+		suite.NoError(err)
+		suite.Len(name, utils.MaxServiceUserNameLength)
+		suite.True(strings.HasSuffix(name, suite.kafkaHandler.createSuffix(&application, "")[:MinServiceUserSuffixLength]))
+		suite.matchesKafkaratorACL(name, &application)
+	})
+}
+
+// matchesKafkaratorACL checks that the name matches the wildcard username kafkarator grants topic access to
+func (suite *KafkaHandlerTestSuite) matchesKafkaratorACL(name string, application *aiven_nais_io_v1.AivenApplication) {
+	acl, err := kafka_nais_io_v1.ServiceUserNameWithSuffix(application.GetNamespace(), application.GetName(), "*")
+	suite.Require().NoError(err)
+	suite.True(strings.HasPrefix(name, strings.TrimSuffix(acl, "*")), "%s does not match %s", name, acl)
+}
+
+func (suite *KafkaHandlerTestSuite) TestServiceUserNameCollision() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	name, err := suite.kafkaHandler.serviceUserName(&application, "")
+	suite.Require().NoError(err)
+	suite.kafkaHandler.secrets = fake.NewClientBuilder().WithObjects(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-secret",
+			Namespace: application.GetNamespace(),
+			Labels:    map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType},
+			Annotations: map[string]string{
+				ServiceUserAnnotation: name,
+				PoolAnnotation:        pool,
+			},
+		},
+	}).Build()
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores))
+	suite.mockServiceUsers.On("Get", mock.Anything, name, pool, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{Username: name}, nil)
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: credStoreSecret, Namespace: application.GetNamespace()}}
+
+	err = suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.True(errors.Is(err, utils.UnrecoverableError))
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure))
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestConfigValidate() {
+	suite.NoError(Config{ClusterName: "dev-gcp", ServiceUserSuffixLength: DefaultServiceUserSuffixLength}.Validate())
+	suite.Error(Config{ServiceUserSuffixLength: DefaultServiceUserSuffixLength}.Validate())
+	suite.Error(Config{ClusterName: "Dev_GCP", ServiceUserSuffixLength: DefaultServiceUserSuffixLength}.Validate())
+	suite.Error(Config{ClusterName: "dev-gcp", ServiceUserSuffixLength: MinServiceUserSuffixLength - 1}.Validate())
+	suite.Error(Config{ClusterName: "dev-gcp", ServiceUserSuffixLength: MaxServiceUserSuffixLength + 1}.Validate())
+	suite.Error(Config{ClusterName: "dev-gcp", ServiceUserSuffixLength: DefaultServiceUserSuffixLength, Migration: MigrationConfig{Enabled: true}}.Validate())
+}
+
+func (suite *KafkaHandlerTestSuite) TestMigrateLegacyServiceUser() {
+	const legacyServiceUser = "test-ns.test-app"
+	suite.kafkaHandler.config.Migration = MigrationConfig{Enabled: true, Interval: time.Hour}
+	suite.kafkaHandler.migrationLimiters = newMigrationLimiters([]string{pool}, suite.kafkaHandler.config.Migration)
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	name, err := suite.kafkaHandler.serviceUserName(&application, "")
+	suite.Require().NoError(err)
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores, ServiceUsersGetNotFound))
+	suite.mockServiceUsers.On("Create", mock.Anything, name, pool, mock.Anything, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{Username: name}, nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation: legacyServiceUser,
+				PoolAnnotation:        pool,
+			},
+		},
+	}
+
+	err = suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal(serviceuser.ConventionUnderscore, serviceuser.Convention(name))
+	suite.Equal(name, secret.GetAnnotations()[ServiceUserAnnotation])
+	suite.Equal(legacyServiceUser, secret.GetAnnotations()[LegacyServiceUserAnnotation])
+	suite.NotContains(secret.GetAnnotations(), MigrationDeferredAnnotation)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestMigrationDeferredByRateLimit() {
+	const legacyServiceUser = "test-ns.test-app"
+	suite.kafkaHandler.config.Migration = MigrationConfig{Enabled: true, Interval: time.Hour}
+	suite.kafkaHandler.migrationLimiters = newMigrationLimiters([]string{pool}, suite.kafkaHandler.config.Migration)
+	suite.kafkaHandler.migrationLimiters[pool].Allow()
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			Kafka: &aiven_nais_io_v1.KafkaSpec{
+				Pool: pool,
+			},
+		}).
+		Build()
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ProjectGetCA, GeneratorMakeCredStores))
+	suite.mockServiceUsers.On("Get", mock.Anything, legacyServiceUser, pool, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{Username: legacyServiceUser}, nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation: legacyServiceUser,
+				PoolAnnotation:        pool,
+			},
+		},
+	}
+
+	err := suite.kafkaHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal(legacyServiceUser, secret.GetAnnotations()[ServiceUserAnnotation])
+	suite.Equal(serviceuser.ConventionDot, secret.GetAnnotations()[MigrationDeferredAnnotation])
+	suite.Contains(secret.GetAnnotations(), constants.AivenatorRenewAfterAnnotation)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCleanupReplacedServiceUser() {
+	const replacedServiceUser = "replaced-service-user"
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:         serviceUserName,
+		PoolAnnotation:                pool,
+		ReplacedServiceUserAnnotation: replacedServiceUser,
+	})
+	suite.mockServiceUsers.On("Delete", mock.Anything, mock.Anything, pool, mock.Anything, mock.Anything).
+		Return(nil)
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, replacedServiceUser, pool, mock.Anything, mock.Anything)
+}
+
+func (suite *KafkaHandlerTestSuite) TestCleanupLegacyServiceUser() {
+	const legacyServiceUser = "test-ns.test-app"
+	secret := &v1.Secret{}
+	secret.SetAnnotations(map[string]string{
+		ServiceUserAnnotation:       serviceUserName,
+		PoolAnnotation:              pool,
+		LegacyServiceUserAnnotation: legacyServiceUser,
+	})
+	suite.mockServiceUsers.On("Delete", mock.Anything, mock.Anything, pool, mock.Anything, mock.Anything).
+		Return(nil)
+
+	err := suite.kafkaHandler.Cleanup(suite.ctx, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, pool, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, legacyServiceUser, pool, mock.Anything, mock.Anything)
+}
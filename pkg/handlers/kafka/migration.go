@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// Results of a migration attempt, used in metrics
+const (
+	migrationMigrated = "migrated"
+	migrationDeferred = "deferred"
+)
+
+// MigrationConfig controls moving service users with legacy names to the current naming convention
+type MigrationConfig struct {
+	// Enabled turns on migration of service users with legacy names
+	Enabled bool
+	// Interval is the minimum time between two migrations in the same pool
+	Interval time.Duration
+}
+
+func (c MigrationConfig) Validate() error {
+	if c.Enabled && c.Interval <= 0 {
+		return fmt.Errorf("migration interval must be positive when migration is enabled")
+	}
+	return nil
+}
+
+// newMigrationLimiters allows one migration per interval in each pool, so a pool is never flooded with new service users
+func newMigrationLimiters(projects []string, config MigrationConfig) map[string]*rate.Limiter {
+	limiters := make(map[string]*rate.Limiter, len(projects))
+	if !config.Enabled {
+		return limiters
+	}
+	for _, prj := range projects {
+		limiters[prj] = rate.NewLimiter(rate.Every(config.Interval), 1)
+	}
+	return limiters
+}
+
+// migrationDue decides whether the service user in the secret should be replaced by one in the current naming convention.
+// Migrations held back by the rate limit are marked on the secret, so the secret is synchronized again later.
+func (h KafkaHandler) migrationDue(serviceUserName, projectName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) bool {
+	convention := serviceuser.Convention(serviceUserName)
+	if !h.config.Migration.Enabled || convention == serviceuser.ConventionUnderscore {
+		delete(secret.GetAnnotations(), keys.annotation(MigrationDeferredAnnotation))
+		return false
+	}
+
+	limiter, ok := h.migrationLimiters[projectName]
+	if !ok || !limiter.Allow() {
+		logger.Infof("Migration of service user %s with %s naming convention deferred by rate limit", serviceUserName, convention)
+		secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+			keys.annotation(MigrationDeferredAnnotation): convention,
+		}))
+		observeMigration(projectName, convention, migrationDeferred)
+		return false
+	}
+	return true
+}
+
+// recordMigration keeps the name of the legacy service user on the secret, so it is deleted along with the secret
+func recordMigration(application *aiven_nais_io_v1.AivenApplication, legacyServiceUserName, serviceUserName, projectName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) {
+	convention := serviceuser.Convention(legacyServiceUserName)
+	annotations := secret.GetAnnotations()
+	delete(annotations, keys.annotation(MigrationDeferredAnnotation))
+	secret.SetAnnotations(utils.MergeStringMap(annotations, map[string]string{
+		keys.annotation(LegacyServiceUserAnnotation): legacyServiceUserName,
+	}))
+	logger.Infof("Migrated %s from service user %s with %s naming convention to %s", application.GetName(), legacyServiceUserName, convention, serviceUserName)
+	observeMigration(projectName, convention, migrationMigrated)
+}
+
+// migrationRetry is when a secret with deferred migrations should be synchronized again
+func (h KafkaHandler) migrationRetry(secret *v1.Secret, applied map[string]poolKeys) (time.Time, bool) {
+	for _, keys := range applied {
+		if _, ok := secret.GetAnnotations()[keys.annotation(MigrationDeferredAnnotation)]; ok {
+			return time.Now().Add(h.config.Migration.Interval), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func observeMigration(projectName, convention, result string) {
+	metrics.KafkaServiceUserMigrations.With(prometheus.Labels{
+		metrics.LabelPool:               projectName,
+		metrics.LabelUserNameConvention: convention,
+		metrics.LabelResult:             result,
+	}).Inc()
+}
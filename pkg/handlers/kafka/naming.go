@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	kafka_nais_io_v1 "github.com/nais/liberator/pkg/apis/kafka.nais.io/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+const (
+	// MinServiceUserSuffixLength is as short as the suffix was before it became configurable
+	MinServiceUserSuffixLength = 3
+	// DefaultServiceUserSuffixLength makes collisions between generations unlikely
+	DefaultServiceUserSuffixLength = 6
+	// MaxServiceUserSuffixLength is the longest suffix. Names with long team and application names get a shorter suffix,
+	// since the rest of the name must match the ACLs of kafkarator and is never shortened.
+	MaxServiceUserSuffixLength = 12
+)
+
+// Validate checks the settings that must be right for service user names to be unique across clusters
+func (c Config) Validate() error {
+	if c.ClusterName == "" {
+		return fmt.Errorf("cluster name is required")
+	}
+	if errs := validation.IsDNS1123Label(c.ClusterName); len(errs) > 0 {
+		return fmt.Errorf("invalid cluster name '%s': %s", c.ClusterName, strings.Join(errs, ", "))
+	}
+	if c.ServiceUserSuffixLength < MinServiceUserSuffixLength || c.ServiceUserSuffixLength > MaxServiceUserSuffixLength {
+		return fmt.Errorf("service user suffix length must be between %d and %d", MinServiceUserSuffixLength, MaxServiceUserSuffixLength)
+	}
+	return c.Migration.Validate()
+}
+
+func (h KafkaHandler) suffixLength() int {
+	if h.config.ServiceUserSuffixLength == 0 {
+		return DefaultServiceUserSuffixLength
+	}
+	return h.config.ServiceUserSuffixLength
+}
+
+// createSuffix derives a suffix from the generation of the application and the cluster it runs in,
+// so every generation in every cluster gets its own service user. A service user replacing another one with an expiring
+// certificate also has the replaced name in the seed.
+func (h KafkaHandler) createSuffix(application *aiven_nais_io_v1.AivenApplication, replaces string) string {
+	seed := fmt.Sprintf("%s/%d", h.config.ClusterName, application.Generation)
+	if replaces != "" {
+		seed = fmt.Sprintf("%s/%s", seed, replaces)
+	}
+	sum := sha256.Sum256([]byte(seed))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:h.suffixLength()]
+}
+
+// serviceUserName builds the name of a new service user. The team_app_hash_ prefix is matched by the ACLs of kafkarator,
+// so the suffix is shortened when the name would be too long.
+func (h KafkaHandler) serviceUserName(application *aiven_nais_io_v1.AivenApplication, replaces string) (string, error) {
+	prefix, err := kafka_nais_io_v1.ServiceUserNameWithSuffix(application.GetNamespace(), application.GetName(), "")
+	if err != nil {
+		return "", err
+	}
+	suffix := h.createSuffix(application, replaces)
+	room := utils.MaxServiceUserNameLength - len(prefix)
+	if room < MinServiceUserSuffixLength {
+		return "", fmt.Errorf("service user name prefix %s leaves no room for a suffix", prefix)
+	}
+	if len(suffix) > room {
+		suffix = suffix[:room]
+	}
+	return prefix + suffix, nil
+}
+
+// ownerOf returns the name of another secret using the service user in the given pool, if any
+func (h KafkaHandler) ownerOf(ctx context.Context, serviceUserName, projectName string, secret *v1.Secret) (string, error) {
+	if h.secrets == nil {
+		return "", nil
+	}
+
+	var secrets v1.SecretList
+	err := metrics.ObserveKubernetesLatency("Secret_List", func() error {
+		return h.secrets.List(ctx, &secrets, client.InNamespace(secret.GetNamespace()), client.MatchingLabels{
+			constants.SecretTypeLabel: constants.AivenatorSecretType,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, other := range secrets.Items {
+		if other.GetName() == secret.GetName() {
+			continue
+		}
+		annotations := other.GetAnnotations()
+		for key, value := range annotations {
+			prefix, ok := poolPrefixFor(key)
+			if ok && value == serviceUserName && annotations[prefix+PoolAnnotation] == projectName {
+				return other.GetName(), nil
+			}
+		}
+	}
+	return "", nil
+}
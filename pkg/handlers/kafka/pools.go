@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+var namePattern = regexp.MustCompile("[^a-z0-9]")
+
+// poolKeys decides the secret keys and annotations used for a pool.
+// The pool in the AivenApplication spec uses the plain keys, while additional pools get keys prefixed with the pool name.
+type poolKeys struct {
+	envPrefix string
+	keyPrefix string
+}
+
+func prefixedPoolKeys(pool string) poolKeys {
+	return poolKeys{
+		envPrefix: strings.ToUpper(namePattern.ReplaceAllString(pool, "_")),
+		keyPrefix: namePattern.ReplaceAllString(pool, "-"),
+	}
+}
+
+func (k poolKeys) annotation(key string) string {
+	return k.fileKey(key)
+}
+
+func (k poolKeys) envKey(key string) string {
+	if k.envPrefix == "" {
+		return key
+	}
+	return fmt.Sprintf("%s_%s", k.envPrefix, key)
+}
+
+func (k poolKeys) fileKey(key string) string {
+	if k.keyPrefix == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", k.keyPrefix, key)
+}
+
+func (k poolKeys) env(values map[string]string) map[string]string {
+	prefixed := make(map[string]string, len(values))
+	for key, value := range values {
+		prefixed[k.envKey(key)] = value
+	}
+	return prefixed
+}
+
+func (k poolKeys) files(values map[string][]byte) map[string][]byte {
+	prefixed := make(map[string][]byte, len(values))
+	for key, value := range values {
+		prefixed[k.fileKey(key)] = value
+	}
+	return prefixed
+}
+
+// removeEnv deletes variables of a feature that is turned off. Values from earlier synchronizations are in Data.
+func (k poolKeys) removeEnv(secret *v1.Secret, keys ...string) {
+	for _, key := range keys {
+		delete(secret.Data, k.envKey(key))
+	}
+}
+
+// removeFiles deletes files of a feature that is turned off
+func (k poolKeys) removeFiles(secret *v1.Secret, files ...string) {
+	for _, file := range files {
+		delete(secret.Data, k.fileKey(file))
+	}
+}
+
+// additionalPools returns the pools listed in AdditionalPoolsAnnotation, excluding the pool from the spec
+func additionalPools(application *aiven_nais_io_v1.AivenApplication) []string {
+	value, ok := application.GetAnnotations()[AdditionalPoolsAnnotation]
+	if !ok {
+		return nil
+	}
+	pools := make([]string, 0)
+	seen := map[string]bool{application.Spec.Kafka.Pool: true}
+	for _, pool := range strings.Split(value, ",") {
+		pool = strings.TrimSpace(pool)
+		if pool == "" || seen[pool] {
+			continue
+		}
+		seen[pool] = true
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+// poolPrefixFor returns the pool prefix of a (possibly pool-prefixed) service user annotation,
+// used to find the other annotations belonging to the same pool
+func poolPrefixFor(annotation string) (string, bool) {
+	if !strings.HasSuffix(annotation, ServiceUserAnnotation) {
+		return "", false
+	}
+	return strings.TrimSuffix(annotation, ServiceUserAnnotation), true
+}
@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/aiven/quota"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// KafkaQuotaCondition reports the quotas applied to the service users of an AivenApplication
+const KafkaQuotaCondition aiven_nais_io_v1.AivenApplicationConditionType = "KafkaQuota"
+
+// Keys accepted in QuotaAnnotation
+const (
+	quotaConsumerByteRate  = "consumerByteRate"
+	quotaProducerByteRate  = "producerByteRate"
+	quotaRequestPercentage = "requestPercentage"
+)
+
+// desiredQuota merges the overrides in QuotaAnnotation with the cluster defaults
+func (h KafkaHandler) desiredQuota(application *aiven_nais_io_v1.AivenApplication) (quota.Quota, error) {
+	desired := h.config.Quota
+	value, ok := application.GetAnnotations()[QuotaAnnotation]
+	if !ok {
+		return desired, nil
+	}
+
+	for _, setting := range strings.Split(value, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		key, rawValue, found := strings.Cut(setting, "=")
+		if !found {
+			return desired, fmt.Errorf("invalid quota setting '%s', expected key=value: %w", setting, utils.UnrecoverableError)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rawValue), 64)
+		if err != nil || rate < 0 {
+			return desired, fmt.Errorf("invalid value for quota setting '%s': %w", key, utils.UnrecoverableError)
+		}
+		switch strings.TrimSpace(key) {
+		case quotaConsumerByteRate:
+			desired.ConsumerByteRate = rate
+		case quotaProducerByteRate:
+			desired.ProducerByteRate = rate
+		case quotaRequestPercentage:
+			desired.RequestPercentage = rate
+		default:
+			return desired, fmt.Errorf("unsupported quota setting '%s': %w", key, utils.UnrecoverableError)
+		}
+	}
+	return desired, nil
+}
+
+// applyQuota makes sure the quota of the service user matches the desired quota, and records it on the secret
+func (h KafkaHandler) applyQuota(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, desired quota.Quota, serviceUserName, projectName, serviceName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) error {
+	annotation := keys.annotation(AppliedQuotaAnnotation)
+
+	if desired.IsZero() {
+		if _, ok := secret.GetAnnotations()[annotation]; !ok {
+			return nil
+		}
+		err := h.deleteQuota(ctx, serviceUserName, projectName, serviceName, logger)
+		if err != nil {
+			return utils.AivenFail("DeleteQuota", application, err, false, logger)
+		}
+		delete(secret.GetAnnotations(), annotation)
+		return nil
+	}
+
+	current, err := h.quota.Get(ctx, serviceUserName, projectName, serviceName)
+	if err != nil && !aiven.IsNotFound(err) {
+		return utils.AivenFail("GetQuota", application, err, false, logger)
+	}
+	if current == nil || *current != desired {
+		err = h.quota.Set(ctx, serviceUserName, projectName, serviceName, desired)
+		if err != nil {
+			return utils.AivenFail("SetQuota", application, err, false, logger)
+		}
+		logger.Infof("Set quota %s for service user %s", desired, serviceUserName)
+	}
+
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		annotation: desired.String(),
+	}))
+	return nil
+}
+
+// deleteQuota removes the quota of a service user, tolerating that it is already gone
+func (h KafkaHandler) deleteQuota(ctx context.Context, serviceUserName, projectName, serviceName string, logger log.FieldLogger) error {
+	err := h.quota.Delete(ctx, serviceUserName, projectName, serviceName)
+	if err != nil && !aiven.IsNotFound(err) {
+		return err
+	}
+	logger.Infof("Deleted quota for service user %s", serviceUserName)
+	return nil
+}
+
+// reportQuotas adds a status condition listing the quotas recorded on the secret for each applied pool
+func reportQuotas(application *aiven_nais_io_v1.AivenApplication, secret *v1.Secret, applied map[string]poolKeys) {
+	quotas := make([]string, 0, len(applied))
+	for pool, keys := range applied {
+		if value, ok := secret.GetAnnotations()[keys.annotation(AppliedQuotaAnnotation)]; ok {
+			quotas = append(quotas, fmt.Sprintf("%s: %s", pool, value))
+		}
+	}
+
+	if len(quotas) == 0 {
+		conditions := make([]aiven_nais_io_v1.AivenApplicationCondition, 0, len(application.Status.Conditions))
+		for _, condition := range application.Status.Conditions {
+			if condition.Type != KafkaQuotaCondition {
+				conditions = append(conditions, condition)
+			}
+		}
+		application.Status.Conditions = conditions
+		return
+	}
+
+	sort.Strings(quotas)
+	application.Status.AddCondition(aiven_nais_io_v1.AivenApplicationCondition{
+		Type:    KafkaQuotaCondition,
+		Status:  v1.ConditionTrue,
+		Reason:  "Applied",
+		Message: strings.Join(quotas, "; "),
+	})
+}
@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/aiven/schemaregistry"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+const allSubjects = "Subject:*"
+
+// schemaRegistryACLKey identifies an ACL entry regardless of its id
+type schemaRegistryACLKey struct {
+	permission string
+	resource   string
+}
+
+func (k schemaRegistryACLKey) String() string {
+	return fmt.Sprintf("%s %s", k.permission, k.resource)
+}
+
+// desiredSchemaRegistryACLs grants read access to all subjects, and write access to the subject prefixes in SchemaRegistryWriteAnnotation
+func desiredSchemaRegistryACLs(application *aiven_nais_io_v1.AivenApplication) ([]schemaRegistryACLKey, error) {
+	desired := []schemaRegistryACLKey{{permission: schemaregistry.PermissionRead, resource: allSubjects}}
+	value, ok := application.GetAnnotations()[SchemaRegistryWriteAnnotation]
+	if !ok {
+		return desired, nil
+	}
+
+	for _, prefix := range strings.Split(value, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if strings.ContainsAny(prefix, "* \t") {
+			return nil, fmt.Errorf("invalid schema registry subject prefix '%s': %w", prefix, utils.UnrecoverableError)
+		}
+		desired = append(desired, schemaRegistryACLKey{permission: schemaregistry.PermissionWrite, resource: fmt.Sprintf("Subject:%s*", prefix)})
+	}
+	return desired, nil
+}
+
+// applySchemaRegistryACLs makes sure the service user has exactly the desired ACL entries, and records them on the secret
+func (h KafkaHandler) applySchemaRegistryACLs(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, desired []schemaRegistryACLKey, serviceUserName, projectName, serviceName string, keys poolKeys, secret *v1.Secret, logger log.FieldLogger) error {
+	acls, err := h.schemaRegistry.List(ctx, projectName, serviceName)
+	if err != nil {
+		return utils.AivenFail("ListSchemaRegistryACLs", application, err, false, logger)
+	}
+
+	existing := make(map[schemaRegistryACLKey]string)
+	for _, acl := range acls {
+		if acl.Username == serviceUserName {
+			existing[schemaRegistryACLKey{permission: acl.Permission, resource: acl.Resource}] = acl.ID
+		}
+	}
+
+	applied := make([]string, 0, len(desired))
+	for _, key := range desired {
+		applied = append(applied, key.String())
+		if _, ok := existing[key]; ok {
+			delete(existing, key)
+			continue
+		}
+		err = h.schemaRegistry.Create(ctx, projectName, serviceName, aiven.CreateKafkaSchemaRegistryACLRequest{
+			Permission: key.permission,
+			Resource:   key.resource,
+			Username:   serviceUserName,
+		})
+		if err != nil {
+			return utils.AivenFail("CreateSchemaRegistryACL", application, err, false, logger)
+		}
+		logger.Infof("Granted %s for service user %s", key, serviceUserName)
+	}
+
+	for key, id := range existing {
+		err = h.schemaRegistry.Delete(ctx, projectName, serviceName, id)
+		if err != nil && !aiven.IsNotFound(err) {
+			return utils.AivenFail("DeleteSchemaRegistryACL", application, err, false, logger)
+		}
+		logger.Infof("Revoked %s for service user %s", key, serviceUserName)
+	}
+
+	sort.Strings(applied)
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		keys.annotation(AppliedSchemaRegistryACLAnnotation): strings.Join(applied, ", "),
+	}))
+	return nil
+}
+
+// deleteSchemaRegistryACLs removes all ACL entries of a service user, tolerating that they are already gone
+func (h KafkaHandler) deleteSchemaRegistryACLs(ctx context.Context, serviceUserName, projectName, serviceName string, logger log.FieldLogger) error {
+	acls, err := h.schemaRegistry.List(ctx, projectName, serviceName)
+	if err != nil {
+		if aiven.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	for _, acl := range acls {
+		if acl.Username != serviceUserName {
+			continue
+		}
+		err = h.schemaRegistry.Delete(ctx, projectName, serviceName, acl.ID)
+		if err != nil && !aiven.IsNotFound(err) {
+			return err
+		}
+	}
+	logger.Infof("Deleted schema registry ACLs for service user %s", serviceUserName)
+	return nil
+}
@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"fmt"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+
+	"github.com/nais/aivenator/pkg/certificate"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// storeFiles are the credential store formats requested by an application, and the files they are written to
+type storeFiles struct {
+	certificate.Formats
+	keystore   string
+	truststore string
+}
+
+func storeFormats(application *aiven_nais_io_v1.AivenApplication) (storeFiles, error) {
+	files := storeFiles{
+		Formats:    certificate.DefaultFormats(),
+		keystore:   KafkaKeystore,
+		truststore: KafkaTruststore,
+	}
+	annotations := application.GetAnnotations()
+
+	if value, ok := annotations[KeystoreFormatAnnotation]; ok {
+		format, err := certificate.ParseStoreFormat(value)
+		if err != nil {
+			return files, fmt.Errorf("invalid keystore format: %s: %w", err, utils.UnrecoverableError)
+		}
+		files.Keystore = format
+		if format == certificate.JKS {
+			files.keystore = KafkaJKSKeystore
+		}
+	}
+
+	// Without an explicit format, keep writing a PKCS12 truststore with the .jks name existing clients expect
+	if value, ok := annotations[TruststoreFormatAnnotation]; ok {
+		format, err := certificate.ParseStoreFormat(value)
+		if err != nil {
+			return files, fmt.Errorf("invalid truststore format: %s: %w", err, utils.UnrecoverableError)
+		}
+		files.Truststore = format
+		if format == certificate.PKCS12 {
+			files.truststore = KafkaPKCS12Truststore
+		}
+	}
+
+	files.PEM = annotations[PEMBundlesAnnotation] == "true"
+	return files, nil
+}
@@ -0,0 +1,164 @@
+package kafkaconnect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/aiven/service"
+	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/annotations"
+	"github.com/nais/aivenator/pkg/policy"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// Annotations
+const (
+	// Name of the Kafka Connect service to provision credentials for, set on the AivenApplication
+	InstanceAnnotation     = "kafkaconnect.aiven.nais.io/instance"
+	ServiceUserAnnotation  = "kafkaconnect.aiven.nais.io/serviceUser"
+	ProjectAnnotation      = "kafkaconnect.aiven.nais.io/project"
+	ServiceAnnotation      = "kafkaconnect.aiven.nais.io/service"
+	AddressRouteAnnotation = "kafkaconnect.aiven.nais.io/addressRoute"
+)
+
+// Environment variables
+const (
+	KafkaConnectUser     = "KAFKA_CONNECT_USERNAME"
+	KafkaConnectPassword = "KAFKA_CONNECT_PASSWORD"
+	KafkaConnectURI      = "KAFKA_CONNECT_URI"
+)
+
+func NewKafkaConnectHandler(ctx context.Context, aiven *aiven.Client, projectName string, addressRoutes service.AddressRoutes, secrets client.Reader, authorizer policy.Authorizer) KafkaConnectHandler {
+	return KafkaConnectHandler{
+		serviceuser: serviceuser.NewManager(ctx, aiven.ServiceUsers),
+		service:     service.NewManager(aiven.Services, addressRoutes),
+		secrets:     secrets,
+		authorizer:  authorizer,
+		projectName: projectName,
+	}
+}
+
+// KafkaConnectHandler provides credentials for the REST API of Kafka Connect services. Aiven has no access control
+// for connectors, so every service user may manage all connectors of a service, whichever namespace registered them.
+type KafkaConnectHandler struct {
+	serviceuser serviceuser.ServiceUserManager
+	service     service.ServiceManager
+	secrets     client.Reader
+	authorizer  policy.Authorizer
+	projectName string
+}
+
+func (h KafkaConnectHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, secret *v1.Secret, logger log.FieldLogger) error {
+	logger = logger.WithFields(log.Fields{"handler": "kafkaconnect"})
+	serviceName, ok := application.GetAnnotations()[InstanceAnnotation]
+	if !ok || serviceName == "" {
+		return nil
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"project": h.projectName,
+		"service": serviceName,
+	})
+
+	err := policy.Check(h.authorizer, application, policy.KafkaConnect, serviceName, "", logger)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := h.service.GetServiceAddresses(ctx, h.projectName, serviceName)
+	if err != nil {
+		return utils.AivenFail("GetService", application, err, true, logger)
+	}
+	if addresses.KafkaConnect == "" {
+		err := fmt.Errorf("service %s has no Kafka Connect REST API: %w", serviceName, utils.UnrecoverableError)
+		utils.LocalFail("ResolveKafkaConnectAddress", application, err, logger)
+		return err
+	}
+	annotations.SetAddressRoute(secret, AddressRouteAnnotation, addresses.Route(service.ComponentKafkaConnect))
+
+	serviceUserName := serviceUserName(application)
+	aivenUser, err := h.serviceuser.Get(ctx, serviceUserName, h.projectName, serviceName, logger)
+	if err != nil {
+		if !aiven.IsNotFound(err) {
+			return utils.AivenFail("GetServiceUser", application, err, false, logger)
+		}
+		aivenUser, err = h.serviceuser.Create(ctx, serviceUserName, h.projectName, serviceName, nil, logger)
+		if err != nil {
+			return utils.AivenFail("CreateServiceUser", application, err, false, logger)
+		}
+		logger.Infof("Created service user %s", aivenUser.Username)
+	}
+
+	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
+		ServiceUserAnnotation: aivenUser.Username,
+		ProjectAnnotation:     h.projectName,
+		ServiceAnnotation:     serviceName,
+	}))
+
+	secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
+		KafkaConnectUser:     aivenUser.Username,
+		KafkaConnectPassword: aivenUser.Password,
+		KafkaConnectURI:      addresses.KafkaConnect,
+	})
+
+	controllerutil.AddFinalizer(secret, constants.AivenatorFinalizer)
+
+	return nil
+}
+
+// serviceUserName includes the namespace, since a Connect service is shared by applications in many namespaces
+func serviceUserName(application *aiven_nais_io_v1.AivenApplication) string {
+	return utils.ApplicationServiceUserName(application.GetNamespace(), application.GetName(), "")
+}
+
+// Cleanup deletes the service user when no other secret has credentials for it
+func (h KafkaConnectHandler) Cleanup(ctx context.Context, secret *v1.Secret, logger *log.Entry) error {
+	annotations := secret.GetAnnotations()
+	serviceUserName, ok := annotations[ServiceUserAnnotation]
+	if !ok {
+		return nil
+	}
+	projectName, okProject := annotations[ProjectAnnotation]
+	serviceName, okService := annotations[ServiceAnnotation]
+	if !okProject || !okService {
+		return fmt.Errorf("missing project or service annotation on secret %s in namespace %s, unable to delete service user %s",
+			secret.GetName(), secret.GetNamespace(), serviceUserName)
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"handler": "kafkaconnect",
+		"project": projectName,
+		"service": serviceName,
+	})
+	used, err := utils.ServiceUserInUse(ctx, h.secrets, secret, map[string]string{
+		ServiceUserAnnotation: serviceUserName,
+		ProjectAnnotation:     projectName,
+		ServiceAnnotation:     serviceName,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to check if service user %s is in use: %w", serviceUserName, err)
+	}
+	if used {
+		logger.Infof("Service user %s is used by other secrets, keeping it", serviceUserName)
+		return nil
+	}
+
+	err = h.serviceuser.Delete(ctx, serviceUserName, projectName, serviceName, logger)
+	if err != nil {
+		if aiven.IsNotFound(err) {
+			logger.Infof("Service user %s does not exist", serviceUserName)
+			return nil
+		}
+		return err
+	}
+	logger.Infof("Deleted service user %s", serviceUserName)
+	return nil
+}
@@ -0,0 +1,232 @@
+package kafkaconnect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/aiven/service"
+	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/policy"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+const (
+	appName         = "test-app"
+	namespace       = "team-a"
+	projectName     = "my-project"
+	serviceName     = "connect-shared"
+	serviceURI      = "https://connect-shared.example.com:23456"
+	testServiceUser = "team-a_test-app"
+	servicePassword = "service-password"
+)
+
+type mockContainer struct {
+	serviceUserManager *serviceuser.MockServiceUserManager
+	serviceManager     *service.MockServiceManager
+}
+
+func TestKafkaConnect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KafkaConnect Suite")
+}
+
+var _ = Describe("kafkaconnect.Handler", func() {
+	var logger *log.Entry
+	var applicationBuilder aiven_nais_io_v1.AivenApplicationBuilder
+	var application aiven_nais_io_v1.AivenApplication
+	var secret v1.Secret
+	var handler KafkaConnectHandler
+	var mocks mockContainer
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	BeforeEach(func() {
+		root := log.New()
+		root.Out = GinkgoWriter
+		logger = log.NewEntry(root)
+		applicationBuilder = aiven_nais_io_v1.NewAivenApplicationBuilder(appName, namespace)
+		secret = v1.Secret{}
+		mocks = mockContainer{
+			serviceUserManager: serviceuser.NewMockServiceUserManager(GinkgoT()),
+			serviceManager:     service.NewMockServiceManager(GinkgoT()),
+		}
+		handler = KafkaConnectHandler{
+			serviceuser: mocks.serviceUserManager,
+			service:     mocks.serviceManager,
+			secrets:     fake.NewClientBuilder().Build(),
+			authorizer:  policy.AllowAll{},
+			projectName: projectName,
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	When("it receives an application without Kafka Connect", func() {
+		It("ignores it", func() {
+			application = applicationBuilder.Build()
+			err := handler.Apply(ctx, &application, &secret, logger)
+			Expect(err).To(Succeed())
+			Expect(secret).To(Equal(v1.Secret{}))
+		})
+	})
+
+	When("it receives an application with Kafka Connect", func() {
+		BeforeEach(func() {
+			application = applicationBuilder.
+				WithAnnotation(InstanceAnnotation, serviceName).
+				Build()
+		})
+
+		Context("and the service has no Connect REST API", func() {
+			BeforeEach(func() {
+				mocks.serviceManager.On("GetServiceAddresses", mock.Anything, projectName, serviceName).
+					Return(&service.ServiceAddresses{}, nil)
+			})
+
+			It("fails without retrying", func() {
+				err := handler.Apply(ctx, &application, &secret, logger)
+				Expect(errors.Is(err, utils.UnrecoverableError)).To(BeTrue())
+				Expect(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure)).ToNot(BeNil())
+			})
+		})
+
+		Context("and the service user does not exist", func() {
+			BeforeEach(func() {
+				mocks.serviceManager.On("GetServiceAddresses", mock.Anything, projectName, serviceName).
+					Return(&service.ServiceAddresses{KafkaConnect: serviceURI}, nil)
+				mocks.serviceUserManager.On("Get", mock.Anything, testServiceUser, projectName, serviceName, mock.Anything).
+					Return(nil, aiven.Error{Message: "Not Found", Status: 404})
+				mocks.serviceUserManager.On("Create", mock.Anything, testServiceUser, projectName, serviceName, mock.Anything, mock.Anything).
+					Return(&aiven.ServiceUser{Username: testServiceUser, Password: servicePassword}, nil)
+			})
+
+			It("creates it and writes the credentials", func() {
+				err := handler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.GetAnnotations()).To(HaveKeyWithValue(ServiceUserAnnotation, testServiceUser))
+				Expect(secret.GetAnnotations()).To(HaveKeyWithValue(ProjectAnnotation, projectName))
+				Expect(secret.GetAnnotations()).To(HaveKeyWithValue(ServiceAnnotation, serviceName))
+				Expect(secret.StringData).To(HaveKeyWithValue(KafkaConnectUser, testServiceUser))
+				Expect(secret.StringData).To(HaveKeyWithValue(KafkaConnectPassword, servicePassword))
+				Expect(secret.StringData).To(HaveKeyWithValue(KafkaConnectURI, serviceURI))
+				Expect(secret.GetFinalizers()).To(ContainElement(constants.AivenatorFinalizer))
+			})
+		})
+
+		Context("and the service user exists", func() {
+			BeforeEach(func() {
+				mocks.serviceManager.On("GetServiceAddresses", mock.Anything, projectName, serviceName).
+					Return(&service.ServiceAddresses{KafkaConnect: serviceURI}, nil)
+				mocks.serviceUserManager.On("Get", mock.Anything, testServiceUser, projectName, serviceName, mock.Anything).
+					Return(&aiven.ServiceUser{Username: testServiceUser, Password: servicePassword}, nil)
+			})
+
+			It("reuses it", func() {
+				err := handler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(KafkaConnectUser, testServiceUser))
+			})
+		})
+	})
+
+	When("it receives an application with a long name", func() {
+		It("shortens the service user name and keeps it unique", func() {
+			long := aiven_nais_io_v1.NewAivenApplicationBuilder("an-application-with-a-name-long-enough-to-overflow", "a-team-with-a-long-name").
+				WithAnnotation(InstanceAnnotation, serviceName).
+				Build()
+			other := aiven_nais_io_v1.NewAivenApplicationBuilder("an-application-with-a-name-long-enough-to-overflow-too", "a-team-with-a-long-name").
+				Build()
+
+			name := serviceUserName(&long)
+			Expect(len(name)).To(Equal(utils.MaxServiceUserNameLength))
+			Expect(name).To(HavePrefix("a-team-with-a-long-name_an-application-with-a-name-"))
+			Expect(name).ToNot(Equal(serviceUserName(&other)))
+		})
+
+		It("keeps namespace and application apart", func() {
+			first := aiven_nais_io_v1.NewAivenApplicationBuilder("c", "a-b").Build()
+			second := aiven_nais_io_v1.NewAivenApplicationBuilder("b-c", "a").Build()
+			Expect(serviceUserName(&first)).ToNot(Equal(serviceUserName(&second)))
+		})
+	})
+
+	When("it cleans up a secret", func() {
+		BeforeEach(func() {
+			secret.ObjectMeta = metav1.ObjectMeta{
+				Name:      "old-secret",
+				Namespace: namespace,
+				Annotations: map[string]string{
+					ServiceUserAnnotation: testServiceUser,
+					ProjectAnnotation:     projectName,
+					ServiceAnnotation:     serviceName,
+				},
+			}
+		})
+
+		It("deletes the service user in the annotations", func() {
+			mocks.serviceUserManager.On("Delete", mock.Anything, testServiceUser, projectName, serviceName, mock.Anything).
+				Return(nil)
+
+			Expect(handler.Cleanup(ctx, &secret, logger)).To(Succeed())
+		})
+
+		It("keeps the service user when another secret uses it", func() {
+			current := secret.DeepCopy()
+			current.SetName("new-secret")
+			current.SetLabels(map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType})
+			handler.secrets = fake.NewClientBuilder().WithObjects(current).Build()
+
+			Expect(handler.Cleanup(ctx, &secret, logger)).To(Succeed())
+			mocks.serviceUserManager.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("keeps the service user when a secret in another namespace uses it", func() {
+			other := secret.DeepCopy()
+			other.SetNamespace("team-b")
+			other.SetLabels(map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType})
+			handler.secrets = fake.NewClientBuilder().WithObjects(other).Build()
+
+			Expect(handler.Cleanup(ctx, &secret, logger)).To(Succeed())
+			mocks.serviceUserManager.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("deletes the service user when secrets of other applications remain", func() {
+			other := secret.DeepCopy()
+			other.SetNamespace("team-b")
+			other.SetLabels(map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType})
+			other.GetAnnotations()[ServiceUserAnnotation] = "team-b_test-app"
+			handler.secrets = fake.NewClientBuilder().WithObjects(&secret, other).Build()
+			mocks.serviceUserManager.On("Delete", mock.Anything, testServiceUser, projectName, serviceName, mock.Anything).
+				Return(nil)
+
+			Expect(handler.Cleanup(ctx, &secret, logger)).To(Succeed())
+		})
+
+		It("fails when the service annotation is missing", func() {
+			delete(secret.GetAnnotations(), ServiceAnnotation)
+
+			Expect(handler.Cleanup(ctx, &secret, logger)).ToNot(Succeed())
+		})
+
+		It("ignores secrets without Kafka Connect", func() {
+			secret = v1.Secret{}
+			Expect(handler.Cleanup(ctx, &secret, logger)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,253 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// IndexPatternsAnnotation on the AivenApplication has comma separated index patterns for the service user of the
+// application, overriding those of its namespace. The OpenSearch spec has no field for them.
+const IndexPatternsAnnotation = "opensearch.aiven.nais.io/indexPatterns"
+
+// DefaultIndexPatterns grant access to all indices, including system indices starting with an underscore
+var DefaultIndexPatterns = []string{"_*", "*"}
+
+// Config holds the operator level settings for the OpenSearch handler
+type Config struct {
+	// DefaultIndexPatterns are the indices service users get access to, for namespaces without IndexPatterns
+	DefaultIndexPatterns []string
+	// IndexPatterns are the indices service users in each namespace get access to.
+	// Shared service users serve all applications in a namespace, so patterns are set per namespace, and applications
+	// with their own service user may override them with IndexPatternsAnnotation.
+	IndexPatterns map[string][]string
+	// PerApplicationUsers gives each application its own service user, instead of one per namespace and access level
+	PerApplicationUsers bool
+}
+
+func (c Config) indexPatterns(namespace string) []string {
+	if patterns, ok := c.IndexPatterns[namespace]; ok && len(patterns) > 0 {
+		return patterns
+	}
+	if len(c.DefaultIndexPatterns) > 0 {
+		return c.DefaultIndexPatterns
+	}
+	return DefaultIndexPatterns
+}
+
+// applicationIndexPatterns reads the index patterns of an application with its own service user from the annotation, or
+// falls back to the patterns of its namespace. Shared service users ignore the annotation, since applications sharing
+// them would keep overwriting each other's patterns.
+func (c Config) applicationIndexPatterns(application *aiven_nais_io_v1.AivenApplication, logger log.FieldLogger) ([]string, error) {
+	value, ok := application.GetAnnotations()[IndexPatternsAnnotation]
+	if !ok {
+		return c.indexPatterns(application.GetNamespace()), nil
+	}
+	if !c.PerApplicationUsers {
+		logger.Warnf("Ignoring annotation %s, service users are shared by the applications in the namespace", IndexPatternsAnnotation)
+		return c.indexPatterns(application.GetNamespace()), nil
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.ContainsAny(pattern, " \t\n") {
+			return nil, fmt.Errorf("index pattern %q in annotation %s contains whitespace: %w", pattern, IndexPatternsAnnotation, utils.UnrecoverableError)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("annotation %s has no index patterns: %w", IndexPatternsAnnotation, utils.UnrecoverableError)
+	}
+	return patterns, nil
+}
+
+// dashboardsIndexPattern matches the indices OpenSearch Dashboards keeps saved objects and settings in
+const dashboardsIndexPattern = ".opensearch_dashboards*"
+
+// dashboardsAllowed tells if the access level lets the service user log in to OpenSearch Dashboards.
+// Dashboards need to read their own indices, which write-only users can not.
+func dashboardsAllowed(access string) bool {
+	return access != "write"
+}
+
+// desiredACL grants the access level on the index patterns, and on the dashboards indices when dashboards are granted
+func desiredACL(serviceUserName string, patterns []string, access string, dashboards bool) aiven.OpenSearchACL {
+	rules := make([]aiven.OpenSearchACLRule, 0, len(patterns)+1)
+	for _, pattern := range patterns {
+		rules = append(rules, aiven.OpenSearchACLRule{Index: pattern, Permission: access})
+	}
+	if dashboards {
+		rules = append(rules, aiven.OpenSearchACLRule{Index: dashboardsIndexPattern, Permission: access})
+	}
+	return aiven.OpenSearchACL{
+		Rules:    rules,
+		Username: serviceUserName,
+	}
+}
+
+// setACL replaces the entry of the service user in the ACL config, leaving the entries of other users as they are
+func setACL(config aiven.OpenSearchACLConfig, acl aiven.OpenSearchACL) aiven.OpenSearchACLConfig {
+	acls := make([]aiven.OpenSearchACL, 0, len(config.ACLs)+1)
+	for _, existing := range config.ACLs {
+		if existing.Username != acl.Username {
+			acls = append(acls, existing)
+		}
+	}
+	config.ACLs = append(acls, acl)
+	config.Enabled = true
+	return config
+}
+
+// Kinds of ACL drift, used in metrics
+const (
+	driftMissing  = "missing"
+	driftChanged  = "changed"
+	driftDisabled = "disabled"
+)
+
+// removeACL removes the entry of the service user from the ACL config, if it has one
+func (h OpenSearchHandler) removeACL(ctx context.Context, serviceUserName, projectName, serviceName string) error {
+	release := h.aclLocks.acquire(projectName, serviceName)
+	defer release()
+
+	resp, err := h.openSearchACL.Get(ctx, projectName, serviceName)
+	if err != nil {
+		return err
+	}
+	config := resp.OpenSearchACLConfig
+	acls := make([]aiven.OpenSearchACL, 0, len(config.ACLs))
+	for _, existing := range config.ACLs {
+		if existing.Username != serviceUserName {
+			acls = append(acls, existing)
+		}
+	}
+	if len(acls) == len(config.ACLs) {
+		return nil
+	}
+	config.ACLs = acls
+	_, err = h.openSearchACL.Update(ctx, projectName, serviceName, aiven.OpenSearchACLRequest{
+		OpenSearchACLConfig: config,
+	})
+	return err
+}
+
+// aclDrift compares the entry of the service user in the ACL config with the desired entry, and describes how it differs
+func aclDrift(config aiven.OpenSearchACLConfig, acl aiven.OpenSearchACL) (string, bool) {
+	if !config.Enabled {
+		return driftDisabled, true
+	}
+	for _, existing := range config.ACLs {
+		if existing.Username != acl.Username {
+			continue
+		}
+		if sameRules(existing.Rules, acl.Rules) {
+			return "", false
+		}
+		return driftChanged, true
+	}
+	return driftMissing, true
+}
+
+func sameRules(a, b []aiven.OpenSearchACLRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	rules := make(map[aiven.OpenSearchACLRule]int, len(a))
+	for _, rule := range a {
+		rules[rule]++
+	}
+	for _, rule := range b {
+		if rules[rule] == 0 {
+			return false
+		}
+		rules[rule]--
+	}
+	return true
+}
+
+// aclUpdateAttempts is how many times an ACL update is tried when it is lost to a concurrent update outside this process
+const aclUpdateAttempts = 3
+
+// serviceLocks serializes the read-modify-write of ACL configs, which cover all users of a service
+type serviceLocks struct {
+	lock  sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newServiceLocks() *serviceLocks {
+	return &serviceLocks{locks: make(map[string]*sync.Mutex)}
+}
+
+// acquire locks the service and returns the function releasing it
+func (l *serviceLocks) acquire(projectName, serviceName string) func() {
+	key := projectName + "/" + serviceName
+	l.lock.Lock()
+	serviceLock, ok := l.locks[key]
+	if !ok {
+		serviceLock = &sync.Mutex{}
+		l.locks[key] = serviceLock
+	}
+	l.lock.Unlock()
+
+	serviceLock.Lock()
+	return serviceLock.Unlock
+}
+
+// reconcileACL updates the ACL config of the service when the entry of the service user differs from the desired entry.
+// Differences for existing users are drift, made outside aivenator or by a changed access level, and are counted.
+func (h OpenSearchHandler) reconcileACL(ctx context.Context, acl aiven.OpenSearchACL, created bool, projectName string, serviceName string, logger log.FieldLogger) error {
+	release := h.aclLocks.acquire(projectName, serviceName)
+	defer release()
+
+	resp, err := h.openSearchACL.Get(ctx, projectName, serviceName)
+	if err != nil {
+		return err
+	}
+	drift, ok := aclDrift(resp.OpenSearchACLConfig, acl)
+	if !ok {
+		return nil
+	}
+
+	for attempt := 1; ok; attempt++ {
+		if attempt > aclUpdateAttempts {
+			return fmt.Errorf("ACL entry of service user %s is still %s after %d updates", acl.Username, drift, aclUpdateAttempts)
+		}
+		if attempt > 1 {
+			logger.Warnf("Update of ACL entry of service user %s was lost, entry is %s, retrying", acl.Username, drift)
+		}
+		_, err = h.openSearchACL.Update(ctx, projectName, serviceName, aiven.OpenSearchACLRequest{
+			OpenSearchACLConfig: setACL(resp.OpenSearchACLConfig, acl),
+		})
+		if err != nil {
+			return err
+		}
+
+		resp, err = h.openSearchACL.Get(ctx, projectName, serviceName)
+		if err != nil {
+			return err
+		}
+		_, ok = aclDrift(resp.OpenSearchACLConfig, acl)
+	}
+
+	if !created {
+		logger.Infof("Corrected ACL of service user %s, entry was %s", acl.Username, drift)
+		metrics.OpenSearchACLDriftCorrected.With(prometheus.Labels{
+			metrics.LabelPool:  projectName,
+			metrics.LabelDrift: drift,
+		}).Inc()
+	}
+	return nil
+}
@@ -2,22 +2,32 @@ package opensearch
 
 import (
 	"context"
-	"fmt"
 	"github.com/aiven/aiven-go-client/v2"
+	"github.com/nais/aivenator/constants"
 	"github.com/nais/aivenator/pkg/aiven/opensearch"
 	"github.com/nais/aivenator/pkg/aiven/project"
 	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/annotations"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // Annotations
 const (
 	ServiceUserAnnotation = "opensearch.aiven.nais.io/serviceUser"
 	ProjectAnnotation     = "opensearch.aiven.nais.io/project"
+	ServiceAnnotation     = "opensearch.aiven.nais.io/service"
+	// Set to "true" by aivenator when the service user belongs to the application alone, and may be deleted with the secret
+	ApplicationUserAnnotation = "opensearch.aiven.nais.io/applicationUser"
+	// Shared service user the application used before getting its own, deleted by aivenator once no secret uses it
+	SharedServiceUserAnnotation = "opensearch.aiven.nais.io/sharedServiceUser"
+	AddressRouteAnnotation      = "opensearch.aiven.nais.io/addressRoute"
 )
 
 // Environment variables
@@ -25,15 +35,22 @@ const (
 	OpenSearchUser     = "OPEN_SEARCH_USERNAME"
 	OpenSearchPassword = "OPEN_SEARCH_PASSWORD"
 	OpenSearchURI      = "OPEN_SEARCH_URI"
+	// Only set when the service has dashboards and the access level allows using them
+	OpenSearchDashboardsURI = "OPEN_SEARCH_DASHBOARDS_URI"
 )
 
-func NewOpenSearchHandler(ctx context.Context, aiven *aiven.Client, projectName string) OpenSearchHandler {
+func NewOpenSearchHandler(ctx context.Context, aiven *aiven.Client, projectName string, addressRoutes service.AddressRoutes, names service.NameResolver, config Config, secrets client.Reader, authorizer policy.Authorizer) OpenSearchHandler {
 	return OpenSearchHandler{
 		project:       project.NewManager(aiven.CA),
 		serviceuser:   serviceuser.NewManager(ctx, aiven.ServiceUsers),
-		service:       service.NewManager(aiven.Services),
+		service:       service.NewManager(aiven.Services, addressRoutes),
+		names:         names,
 		openSearchACL: aiven.OpenSearchACLs,
+		aclLocks:      newServiceLocks(),
+		secrets:       secrets,
+		authorizer:    authorizer,
 		projectName:   projectName,
+		config:        config,
 	}
 }
 
@@ -41,8 +58,13 @@ type OpenSearchHandler struct {
 	project       project.ProjectManager
 	serviceuser   serviceuser.ServiceUserManager
 	service       service.ServiceManager
+	names         service.NameResolver
 	openSearchACL opensearch.ACLManager
+	aclLocks      *serviceLocks
+	secrets       client.Reader
+	authorizer    policy.Authorizer
 	projectName   string
+	config        Config
 }
 
 func (h OpenSearchHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, secret *v1.Secret, logger log.FieldLogger) error {
@@ -52,40 +74,76 @@ func (h OpenSearchHandler) Apply(ctx context.Context, application *aiven_nais_io
 		return nil
 	}
 
-	serviceName := spec.Instance
+	serviceName, err := h.names.ResolveServiceName(service.OpenSearch, application, spec.Instance)
+	if err != nil {
+		utils.LocalFail("ResolveServiceName", application, err, logger)
+		return err
+	}
 
 	logger = logger.WithFields(log.Fields{
 		"project": h.projectName,
 		"service": serviceName,
 	})
 
+	err = policy.Check(h.authorizer, application, policy.OpenSearch, serviceName, spec.Access, logger)
+	if err != nil {
+		return err
+	}
+
+	indexPatterns, err := h.config.applicationIndexPatterns(application, logger)
+	if err != nil {
+		utils.LocalFail("IndexPatterns", application, err, logger)
+		return err
+	}
+
 	addresses, err := h.service.GetServiceAddresses(ctx, h.projectName, serviceName)
 	if err != nil {
 		return utils.AivenFail("GetService", application, err, false, logger)
 	}
 
-	serviceUserName := fmt.Sprintf("%s%s", application.GetNamespace(), utils.SelectSuffix(spec.Access))
+	serviceUserName := h.serviceUserName(application, spec.Access)
+	previousServiceUser := secret.GetAnnotations()[ServiceUserAnnotation]
 
+	created := false
 	aivenUser, err := h.serviceuser.Get(ctx, serviceUserName, h.projectName, serviceName, logger)
 	if err != nil {
-		if aiven.IsNotFound(err) {
-			aivenUser, err = h.serviceuser.Create(ctx, serviceUserName, h.projectName, serviceName, nil, logger)
-			if err != nil {
-				return utils.AivenFail("CreateServiceUser", application, err, false, logger)
-			}
-			err = h.updateACL(ctx, serviceUserName, spec.Access, h.projectName, serviceName)
-			if err != nil {
-				return utils.AivenFail("UpdateACL", application, err, false, logger)
-			}
-		} else {
+		if !aiven.IsNotFound(err) {
 			return utils.AivenFail("GetServiceUser", application, err, false, logger)
 		}
+		aivenUser, err = h.serviceuser.Create(ctx, serviceUserName, h.projectName, serviceName, nil, logger)
+		if err != nil {
+			return utils.AivenFail("CreateServiceUser", application, err, false, logger)
+		}
+		created = true
+	}
+
+	dashboards := addresses.OpenSearchDashboards != "" && dashboardsAllowed(spec.Access)
+	components := []string{service.ComponentOpenSearch}
+	if dashboards {
+		components = append(components, service.ComponentOpenSearchDashboards)
+	}
+	annotations.SetAddressRoute(secret, AddressRouteAnnotation, addresses.Route(components...))
+	err = h.reconcileACL(ctx, desiredACL(serviceUserName, indexPatterns, spec.Access, dashboards), created, h.projectName, serviceName, logger)
+	if err != nil {
+		return utils.AivenFail("UpdateACL", application, err, false, logger)
 	}
 
 	secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
 		ServiceUserAnnotation: aivenUser.Username,
 		ProjectAnnotation:     h.projectName,
+		ServiceAnnotation:     serviceName,
 	}))
+	if h.config.PerApplicationUsers {
+		secret.GetAnnotations()[ApplicationUserAnnotation] = "true"
+		controllerutil.AddFinalizer(secret, constants.AivenatorFinalizer)
+		err = h.migrateSharedServiceUser(ctx, application, spec.Access, serviceName, previousServiceUser, secret, logger)
+		if err != nil {
+			logger.Warnf("Unable to migrate from shared service user: %v", err)
+		}
+	} else {
+		delete(secret.GetAnnotations(), ApplicationUserAnnotation)
+		delete(secret.GetAnnotations(), SharedServiceUserAnnotation)
+	}
 	logger.Infof("Fetched service user %s", aivenUser.Username)
 
 	secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
@@ -93,33 +151,11 @@ func (h OpenSearchHandler) Apply(ctx context.Context, application *aiven_nais_io
 		OpenSearchPassword: aivenUser.Password,
 		OpenSearchURI:      addresses.OpenSearch,
 	})
-
-	return nil
-}
-
-func (h OpenSearchHandler) Cleanup(_ context.Context, _ *v1.Secret, _ *log.Entry) error {
-	return nil
-}
-
-func (h OpenSearchHandler) updateACL(ctx context.Context, serviceUserName string, access string, projectName string, serviceName string) error {
-	resp, err := h.openSearchACL.Get(ctx, projectName, serviceName)
-	if err != nil {
-		return err
-	}
-	config := resp.OpenSearchACLConfig
-	config.Enabled = true
-	config.Add(aiven.OpenSearchACL{
-		Rules: []aiven.OpenSearchACLRule{
-			{Index: "_*", Permission: access},
-			{Index: "*", Permission: access},
-		},
-		Username: serviceUserName,
-	})
-	resp, err = h.openSearchACL.Update(ctx, projectName, serviceName, aiven.OpenSearchACLRequest{
-		OpenSearchACLConfig: config,
-	})
-	if err != nil {
-		return err
+	if dashboards {
+		secret.StringData[OpenSearchDashboardsURI] = addresses.OpenSearchDashboards
+	} else {
+		delete(secret.Data, OpenSearchDashboardsURI)
 	}
+
 	return nil
 }
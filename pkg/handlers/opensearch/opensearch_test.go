@@ -2,15 +2,23 @@ package opensearch
 
 import (
 	"context"
+	"fmt"
 	"github.com/nais/aivenator/pkg/aiven/opensearch"
 	"github.com/nais/aivenator/pkg/aiven/project"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/service"
@@ -53,6 +61,7 @@ type OpenSearchHandlerTestSuite struct {
 	mockServiceUsers   *serviceuser.MockServiceUserManager
 	mockServices       *service.MockServiceManager
 	mockOpenSearchACL  *opensearch.MockACLManager
+	aclConfig          aiven.OpenSearchACLConfig
 	opensearchHandler  OpenSearchHandler
 	applicationBuilder aiven_nais_io_v1.AivenApplicationBuilder
 	mockProjects       *project.MockProjectManager
@@ -88,36 +97,16 @@ func (suite *OpenSearchHandlerTestSuite) addDefaultMocks(enabled map[int]struct{
 	}
 	if _, ok := enabled[OpenSearchACLGet]; ok {
 		suite.mockOpenSearchACL.On("Get", mock.Anything, mock.Anything, mock.Anything).
-			Return(&aiven.OpenSearchACLResponse{
-				OpenSearchACLConfig: aiven.OpenSearchACLConfig{
-					ACLs: []aiven.OpenSearchACL{
-						{
-							Rules:    nil,
-							Username: serviceUserName,
-						},
-					},
-					Enabled:     true,
-					ExtendedAcl: false,
-				},
-			}, nil).Once()
+			Return(func(context.Context, string, string) (*aiven.OpenSearchACLResponse, error) {
+				return &aiven.OpenSearchACLResponse{OpenSearchACLConfig: suite.aclConfig}, nil
+			})
 	}
 	if _, ok := enabled[OpenSearchACLUpdate]; ok {
 		suite.mockOpenSearchACL.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-			Return(&aiven.OpenSearchACLResponse{
-				OpenSearchACLConfig: aiven.OpenSearchACLConfig{
-					ACLs: []aiven.OpenSearchACL{
-						{
-							Rules: []aiven.OpenSearchACLRule{
-								{Index: "*", Permission: access},
-								{Index: "_*", Permission: access},
-							},
-							Username: serviceUserName,
-						},
-					},
-					Enabled:     true,
-					ExtendedAcl: false,
-				},
-			}, nil).Once()
+			Return(func(_ context.Context, _, _ string, req aiven.OpenSearchACLRequest) (*aiven.OpenSearchACLResponse, error) {
+				suite.aclConfig = req.OpenSearchACLConfig
+				return &aiven.OpenSearchACLResponse{OpenSearchACLConfig: suite.aclConfig}, nil
+			})
 	}
 }
 
@@ -125,12 +114,26 @@ func (suite *OpenSearchHandlerTestSuite) SetupTest() {
 	suite.mockServiceUsers = &serviceuser.MockServiceUserManager{}
 	suite.mockServices = &service.MockServiceManager{}
 	suite.mockOpenSearchACL = &opensearch.MockACLManager{}
+	suite.aclConfig = aiven.OpenSearchACLConfig{
+		ACLs: []aiven.OpenSearchACL{
+			{
+				Rules:    nil,
+				Username: serviceUserName,
+			},
+		},
+		Enabled:     true,
+		ExtendedAcl: false,
+	}
 	suite.opensearchHandler = OpenSearchHandler{
 		project:       suite.mockProjects,
 		serviceuser:   suite.mockServiceUsers,
 		service:       suite.mockServices,
+		names:         service.NewDefaultNameResolver(),
 		openSearchACL: suite.mockOpenSearchACL,
+		aclLocks:      newServiceLocks(),
+		authorizer:    policy.AllowAll{},
 		projectName:   projectName,
+		secrets:       fake.NewClientBuilder().Build(),
 	}
 	suite.applicationBuilder = aiven_nais_io_v1.NewAivenApplicationBuilder("test-app", namespace)
 	suite.ctx, suite.cancel = context.WithTimeout(context.Background(), 5*time.Second)
@@ -151,7 +154,7 @@ func (suite *OpenSearchHandlerTestSuite) TestNoOpenSearch() {
 }
 
 func (suite *OpenSearchHandlerTestSuite) TestOpenSearchOk() {
-	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet))
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet, OpenSearchACLGet, OpenSearchACLUpdate))
 	application := suite.applicationBuilder.
 		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
 			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
@@ -169,6 +172,7 @@ func (suite *OpenSearchHandlerTestSuite) TestOpenSearchOk() {
 			Annotations: map[string]string{
 				ProjectAnnotation:     projectName,
 				ServiceUserAnnotation: serviceUserName,
+				ServiceAnnotation:     instance,
 			},
 		},
 		// Check these individually
@@ -318,7 +322,7 @@ func (suite *OpenSearchHandlerTestSuite) TestCorrectServiceUserSelected() {
 
 	for _, t := range testData {
 		suite.Run(t.access, func() {
-			suite.addDefaultMocks(enabled(ServicesGetAddresses))
+			suite.addDefaultMocks(enabled(ServicesGetAddresses, OpenSearchACLGet, OpenSearchACLUpdate))
 			suite.mockServiceUsers.On("Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 				Return(&aiven.ServiceUser{
 					Username: t.username,
@@ -341,7 +345,569 @@ func (suite *OpenSearchHandlerTestSuite) TestCorrectServiceUserSelected() {
 	}
 }
 
+func (suite *OpenSearchHandlerTestSuite) TestACLReconciledForExistingUser() {
+	suite.opensearchHandler.config = Config{
+		IndexPatterns: map[string][]string{namespace: {"team-a-*"}},
+	}
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	username := serviceUserName + "-r"
+	otherACL := aiven.OpenSearchACL{
+		Rules:    []aiven.OpenSearchACLRule{{Index: "*", Permission: "admin"}},
+		Username: "team-b",
+	}
+
+	suite.aclConfig = aiven.OpenSearchACLConfig{
+		ACLs: []aiven.OpenSearchACL{
+			otherACL,
+			{
+				Rules: []aiven.OpenSearchACLRule{
+					{Index: "_*", Permission: access},
+					{Index: "*", Permission: access},
+				},
+				Username: username,
+			},
+		},
+		Enabled: true,
+	}
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet, OpenSearchACLGet, OpenSearchACLUpdate))
+	drift := metrics.OpenSearchACLDriftCorrected.WithLabelValues(projectName, driftChanged)
+	before := testutil.ToFloat64(drift)
+
+	secret := &v1.Secret{}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal([]aiven.OpenSearchACL{
+		otherACL,
+		{
+			Rules:    []aiven.OpenSearchACLRule{{Index: "team-a-*", Permission: access}},
+			Username: username,
+		},
+	}, suite.aclConfig.ACLs)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(before+1, testutil.ToFloat64(drift))
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestACLUnchanged() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	username := serviceUserName + "-r"
+
+	suite.aclConfig = aiven.OpenSearchACLConfig{
+		ACLs: []aiven.OpenSearchACL{
+			{
+				Rules: []aiven.OpenSearchACLRule{
+					{Index: "*", Permission: access},
+					{Index: "_*", Permission: access},
+				},
+				Username: username,
+			},
+		},
+		Enabled: true,
+	}
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet, OpenSearchACLGet))
+
+	secret := &v1.Secret{}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.mockOpenSearchACL.AssertNotCalled(suite.T(), "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestACLUpdateLost() {
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet, OpenSearchACLGet))
+	suite.mockOpenSearchACL.On("Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&aiven.OpenSearchACLResponse{}, nil)
+
+	secret := &v1.Secret{}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.Error(err)
+	suite.NotNil(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationAivenFailure))
+	suite.mockOpenSearchACL.AssertNumberOfCalls(suite.T(), "Update", aclUpdateAttempts)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestConcurrentACLUpdates() {
+	suite.addDefaultMocks(enabled(OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.aclConfig = aiven.OpenSearchACLConfig{Enabled: true}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		username := fmt.Sprintf("team-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acl := desiredACL(username, DefaultIndexPatterns, access, false)
+			suite.NoError(suite.opensearchHandler.reconcileACL(suite.ctx, acl, true, projectName, instance, suite.logger))
+		}()
+	}
+	wg.Wait()
+
+	suite.Len(suite.aclConfig.ACLs, 10)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestPerApplicationUserMigrated() {
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	username := "team-a_test-app-r"
+
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.mockServiceUsers.On("Get", mock.Anything, username, projectName, instance, mock.Anything).
+		Return(nil, aiven.Error{Message: "Service user does not exist", Status: 404})
+	suite.mockServiceUsers.On("Create", mock.Anything, username, projectName, instance, mock.Anything, mock.Anything).
+		Return(&aiven.ServiceUser{Username: username, Password: servicePassword}, nil).Once()
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ServiceUserAnnotation: serviceUserName + "-r",
+				ProjectAnnotation:     projectName,
+				ServiceAnnotation:     instance,
+			},
+		},
+	}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.Equal(username, secret.StringData[OpenSearchUser])
+	suite.Equal(username, secret.GetAnnotations()[ServiceUserAnnotation])
+	suite.Equal("true", secret.GetAnnotations()[ApplicationUserAnnotation])
+	suite.Equal(serviceUserName+"-r", secret.GetAnnotations()[SharedServiceUserAnnotation])
+	suite.Contains(secret.GetFinalizers(), constants.AivenatorFinalizer)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestSharedServiceUserRetired() {
+	const username = "team-a_test-app-r"
+	const shared = serviceUserName + "-r"
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	migrated := func() *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "new-secret",
+				Namespace: namespace,
+				Annotations: map[string]string{
+					ServiceUserAnnotation:       username,
+					ProjectAnnotation:           projectName,
+					ServiceAnnotation:           instance,
+					ApplicationUserAnnotation:   "true",
+					SharedServiceUserAnnotation: shared,
+				},
+			},
+		}
+	}
+	sharedUser := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-app",
+			Namespace: namespace,
+			Labels:    map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType},
+			Annotations: map[string]string{
+				ServiceUserAnnotation: shared,
+				ProjectAnnotation:     projectName,
+				ServiceAnnotation:     instance,
+			},
+		},
+	}
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.mockServiceUsers.On("Get", mock.Anything, username, projectName, instance, mock.Anything).
+		Return(&aiven.ServiceUser{Username: username, Password: servicePassword}, nil)
+
+	suite.Run("still in use", func() {
+		suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(sharedUser).Build()
+		secret := migrated()
+
+		suite.NoError(suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger))
+		suite.Equal(shared, secret.GetAnnotations()[SharedServiceUserAnnotation])
+		suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("unused", func() {
+		suite.opensearchHandler.secrets = fake.NewClientBuilder().Build()
+		suite.mockServiceUsers.On("Delete", mock.Anything, shared, projectName, instance, mock.Anything).Return(nil)
+		secret := migrated()
+
+		suite.NoError(suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger))
+		suite.NotContains(secret.GetAnnotations(), SharedServiceUserAnnotation)
+		suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, shared, projectName, instance, mock.Anything)
+	})
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestSharedServiceUserHeldByAnotherSecret() {
+	const username = "team-a_test-app-r"
+	const shared = serviceUserName + "-r"
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	legacy := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "legacy-secret",
+			Namespace: namespace,
+			Labels:    map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType},
+			Annotations: map[string]string{
+				ServiceUserAnnotation: shared,
+				ProjectAnnotation:     projectName,
+				ServiceAnnotation:     instance,
+			},
+		},
+	}
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.mockServiceUsers.On("Get", mock.Anything, username, projectName, instance, mock.Anything).
+		Return(&aiven.ServiceUser{Username: username, Password: servicePassword}, nil)
+	suite.mockServiceUsers.On("Delete", mock.Anything, shared, projectName, instance, mock.Anything).Return(nil)
+
+	suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(legacy).Build()
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "new-secret", Namespace: namespace}}
+	suite.NoError(suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger))
+	suite.Equal(shared, secret.GetAnnotations()[SharedServiceUserAnnotation])
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	suite.opensearchHandler.secrets = fake.NewClientBuilder().Build()
+	suite.NoError(suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger))
+	suite.NotContains(secret.GetAnnotations(), SharedServiceUserAnnotation)
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, shared, projectName, instance, mock.Anything)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestPerApplicationUserLongName() {
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	application := aiven_nais_io_v1.NewAivenApplicationBuilder("an-application-with-a-very-long-name-indeed", "a-team-with-a-long-name").
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+
+	name := suite.opensearchHandler.serviceUserName(&application, access)
+
+	suite.Len(name, utils.MaxServiceUserNameLength)
+	suite.True(strings.HasSuffix(name, "-r"))
+	suite.NotEqual(name, suite.opensearchHandler.serviceUserName(&application, "readwrite"))
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestCleanup() {
+	const username = "team-a_test-app-r"
+	applicationUser := func(name string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType},
+				Annotations: map[string]string{
+					ServiceUserAnnotation:     username,
+					ProjectAnnotation:         projectName,
+					ServiceAnnotation:         instance,
+					ApplicationUserAnnotation: "true",
+				},
+			},
+		}
+	}
+
+	suite.Run("shared user", func() {
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			ServiceUserAnnotation: serviceUserName,
+			ProjectAnnotation:     projectName,
+			ServiceAnnotation:     instance,
+		}}}
+		suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, secret, suite.logger))
+		suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("user in use by another secret", func() {
+		suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(applicationUser("old-secret"), applicationUser("new-secret")).Build()
+		suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, applicationUser("old-secret"), suite.logger))
+		suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("user in use by a secret in another namespace", func() {
+		other := applicationUser("old-secret")
+		other.SetNamespace("team-b")
+		suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(applicationUser("old-secret"), other).Build()
+		suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, applicationUser("old-secret"), suite.logger))
+		suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("unused user", func() {
+		suite.aclConfig = aiven.OpenSearchACLConfig{
+			Enabled: true,
+			ACLs: []aiven.OpenSearchACL{
+				{Rules: []aiven.OpenSearchACLRule{{Index: "*", Permission: access}}, Username: username},
+				{Rules: []aiven.OpenSearchACLRule{{Index: "*", Permission: access}}, Username: serviceUserName},
+			},
+		}
+		suite.addDefaultMocks(enabled(OpenSearchACLGet, OpenSearchACLUpdate))
+		suite.mockServiceUsers.On("Delete", mock.Anything, username, projectName, instance, mock.Anything).Return(nil)
+		suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(applicationUser("old-secret")).Build()
+
+		suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, applicationUser("old-secret"), suite.logger))
+		suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, username, projectName, instance, mock.Anything)
+		suite.Len(suite.aclConfig.ACLs, 1)
+		suite.Equal(serviceUserName, suite.aclConfig.ACLs[0].Username)
+	})
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestCleanupLegacySecret() {
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	legacy := func(name string) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{constants.SecretTypeLabel: constants.AivenatorSecretType},
+			Annotations: map[string]string{
+				ServiceUserAnnotation: serviceUserName,
+				ProjectAnnotation:     projectName,
+				ServiceAnnotation:     instance,
+			},
+		}}
+	}
+	deleted := legacy("legacy-secret")
+	deleted.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+
+	suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, legacy("legacy-secret"), suite.logger))
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	suite.opensearchHandler.secrets = fake.NewClientBuilder().WithObjects(legacy("other-secret")).Build()
+	suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, deleted, suite.logger))
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	suite.addDefaultMocks(enabled(OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.mockServiceUsers.On("Delete", mock.Anything, serviceUserName, projectName, instance, mock.Anything).Return(nil).Once()
+	suite.opensearchHandler.secrets = fake.NewClientBuilder().Build()
+	suite.NoError(suite.opensearchHandler.Cleanup(suite.ctx, deleted, suite.logger))
+	suite.mockServiceUsers.AssertCalled(suite.T(), "Delete", mock.Anything, serviceUserName, projectName, instance, mock.Anything)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestACLDrift() {
+	acl := aiven.OpenSearchACL{
+		Rules:    []aiven.OpenSearchACLRule{{Index: "*", Permission: "read"}},
+		Username: serviceUserName,
+	}
+	otherRules := aiven.OpenSearchACL{
+		Rules:    []aiven.OpenSearchACLRule{{Index: "*", Permission: "admin"}},
+		Username: serviceUserName,
+	}
+
+	testData := []struct {
+		name   string
+		config aiven.OpenSearchACLConfig
+		drift  string
+		ok     bool
+	}{
+		{name: "unchanged", config: aiven.OpenSearchACLConfig{Enabled: true, ACLs: []aiven.OpenSearchACL{acl}}},
+		{name: "missing", config: aiven.OpenSearchACLConfig{Enabled: true}, drift: driftMissing, ok: true},
+		{name: "changed", config: aiven.OpenSearchACLConfig{Enabled: true, ACLs: []aiven.OpenSearchACL{otherRules}}, drift: driftChanged, ok: true},
+		{name: "disabled", config: aiven.OpenSearchACLConfig{ACLs: []aiven.OpenSearchACL{acl}}, drift: driftDisabled, ok: true},
+	}
+	for _, t := range testData {
+		suite.Run(t.name, func() {
+			drift, ok := aclDrift(t.config, acl)
+			suite.Equal(t.ok, ok)
+			suite.Equal(t.drift, drift)
+		})
+	}
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestIndexPatterns() {
+	config := Config{
+		DefaultIndexPatterns: []string{"shared-*"},
+		IndexPatterns:        map[string][]string{namespace: {"team-a-*", "logs-team-a-*"}},
+	}
+
+	suite.Equal([]string{"team-a-*", "logs-team-a-*"}, config.indexPatterns(namespace))
+	suite.Equal([]string{"shared-*"}, config.indexPatterns("team-b"))
+	suite.Equal(DefaultIndexPatterns, Config{}.indexPatterns(namespace))
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestApplicationIndexPatterns() {
+	config := Config{
+		IndexPatterns:       map[string][]string{namespace: {"team-a-*"}},
+		PerApplicationUsers: true,
+	}
+	annotated := func(value string) *aiven_nais_io_v1.AivenApplication {
+		application := suite.applicationBuilder.WithAnnotation(IndexPatternsAnnotation, value).Build()
+		return &application
+	}
+	plain := suite.applicationBuilder.Build()
+
+	patterns, err := config.applicationIndexPatterns(&plain, suite.logger)
+	suite.NoError(err)
+	suite.Equal([]string{"team-a-*"}, patterns)
+
+	patterns, err = config.applicationIndexPatterns(annotated("test-app-*, logs-test-app-*"), suite.logger)
+	suite.NoError(err)
+	suite.Equal([]string{"test-app-*", "logs-test-app-*"}, patterns)
+
+	_, err = config.applicationIndexPatterns(annotated(" , "), suite.logger)
+	suite.ErrorIs(err, utils.UnrecoverableError)
+
+	config.PerApplicationUsers = false
+	patterns, err = config.applicationIndexPatterns(annotated("test-app-*"), suite.logger)
+	suite.NoError(err)
+	suite.Equal([]string{"team-a-*"}, patterns)
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestApplicationIndexPatternsApplied() {
+	const username = "team-a_test-app-r"
+	suite.opensearchHandler.config.PerApplicationUsers = true
+	application := suite.applicationBuilder.
+		WithAnnotation(IndexPatternsAnnotation, "test-app-*").
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, OpenSearchACLGet, OpenSearchACLUpdate))
+	suite.mockServiceUsers.On("Get", mock.Anything, username, projectName, instance, mock.Anything).
+		Return(&aiven.ServiceUser{Username: username, Password: servicePassword}, nil)
+
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "new-secret", Namespace: namespace}}
+	suite.NoError(suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger))
+
+	for _, acl := range suite.aclConfig.ACLs {
+		if acl.Username == username {
+			suite.Equal([]aiven.OpenSearchACLRule{{Index: "test-app-*", Permission: access}}, acl.Rules)
+			return
+		}
+	}
+	suite.Fail("no ACL entry for service user " + username)
+}
+
 func TestOpenSearchHandler(t *testing.T) {
 	opensearchTestSuite := new(OpenSearchHandlerTestSuite)
 	suite.Run(t, opensearchTestSuite)
 }
+
+func (suite *OpenSearchHandlerTestSuite) TestOpenSearchDashboards() {
+	const dashboardsURI = "https://dashboards.example.com:443"
+	tests := []struct {
+		access         string
+		wantDashboards bool
+	}{
+		{access: "read", wantDashboards: true},
+		{access: "readwrite", wantDashboards: true},
+		{access: "admin", wantDashboards: true},
+		{access: "write", wantDashboards: false},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.access, func() {
+			suite.SetupTest()
+			suite.mockServices.On("GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything).
+				Return(&service.ServiceAddresses{
+					OpenSearch:           serviceURI,
+					OpenSearchDashboards: dashboardsURI,
+				}, nil)
+			suite.addDefaultMocks(enabled(ServiceUsersGet, OpenSearchACLGet, OpenSearchACLUpdate))
+			application := suite.applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+						Instance: instance,
+						Access:   tt.access,
+					},
+				}).
+				Build()
+			secret := &v1.Secret{Data: map[string][]byte{OpenSearchDashboardsURI: []byte("https://old.example.com")}}
+			err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+			suite.NoError(err)
+			dashboardsRule := aiven.OpenSearchACLRule{Index: dashboardsIndexPattern, Permission: tt.access}
+			if tt.wantDashboards {
+				suite.Equal(dashboardsURI, secret.StringData[OpenSearchDashboardsURI])
+				suite.Contains(suite.aclConfig.ACLs[len(suite.aclConfig.ACLs)-1].Rules, dashboardsRule)
+			} else {
+				suite.NotContains(secret.StringData, OpenSearchDashboardsURI)
+				suite.NotContains(secret.Data, OpenSearchDashboardsURI)
+				suite.NotContains(suite.aclConfig.ACLs[len(suite.aclConfig.ACLs)-1].Rules, dashboardsRule)
+			}
+		})
+	}
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestOpenSearchWithoutDashboards() {
+	suite.addDefaultMocks(enabled(ServicesGetAddresses, ServiceUsersGet, OpenSearchACLGet, OpenSearchACLUpdate))
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	// Written by an earlier synchronization, when the service had dashboards
+	secret := &v1.Secret{Data: map[string][]byte{OpenSearchDashboardsURI: []byte("https://dashboards.example.com:443")}}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.NoError(err)
+	suite.NotContains(secret.StringData, OpenSearchDashboardsURI)
+	suite.NotContains(secret.Data, OpenSearchDashboardsURI)
+	for _, rule := range suite.aclConfig.ACLs[len(suite.aclConfig.ACLs)-1].Rules {
+		suite.NotEqual(dashboardsIndexPattern, rule.Index)
+	}
+}
+
+func (suite *OpenSearchHandlerTestSuite) TestPolicyDenied() {
+	suite.opensearchHandler.authorizer = policy.Policy{
+		Rules: []policy.Rule{
+			{Namespaces: []string{"*"}, Service: policy.OpenSearch, Instances: []string{"opensearch-{namespace}-*"}},
+		},
+	}
+	application := suite.applicationBuilder.
+		WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+			OpenSearch: &aiven_nais_io_v1.OpenSearchSpec{
+				Instance: instance,
+				Access:   access,
+			},
+		}).
+		Build()
+	secret := &v1.Secret{}
+	err := suite.opensearchHandler.Apply(suite.ctx, &application, secret, suite.logger)
+
+	suite.ErrorIs(err, utils.UnrecoverableError)
+	suite.NotNil(application.Status.GetConditionOfType(utils.AivenApplicationPolicyDenied))
+	suite.mockServices.AssertNotCalled(suite.T(), "GetServiceAddresses", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockServiceUsers.AssertNotCalled(suite.T(), "Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
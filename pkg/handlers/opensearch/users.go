@@ -0,0 +1,145 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// serviceUserName is shared by all applications in the namespace with the same access level, unless per-application
+// users are enabled. Per-application users include the namespace, since an instance may be shared by many namespaces.
+func (h OpenSearchHandler) serviceUserName(application *aiven_nais_io_v1.AivenApplication, access string) string {
+	if !h.config.PerApplicationUsers {
+		return sharedServiceUserName(application, access)
+	}
+	return utils.ApplicationServiceUserName(application.GetNamespace(), application.GetName(), utils.SelectSuffix(access))
+}
+
+func sharedServiceUserName(application *aiven_nais_io_v1.AivenApplication, access string) string {
+	return fmt.Sprintf("%s%s", application.GetNamespace(), utils.SelectSuffix(access))
+}
+
+// migrateSharedServiceUser moves an application with its own service user off the shared user of its namespace and
+// access level. The shared user is recorded on the secret while the secret used it before, or other secrets still use
+// it, and retired in a later synchronization once no secret uses it, giving running applications time to pick up
+// their new credentials.
+func (h OpenSearchHandler) migrateSharedServiceUser(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, access, serviceName, previous string, secret *v1.Secret, logger log.FieldLogger) error {
+	annotations := secret.GetAnnotations()
+	if recorded, ok := annotations[SharedServiceUserAnnotation]; ok {
+		retired, err := h.retireServiceUser(ctx, recorded, h.projectName, serviceName, secret, logger)
+		if err != nil {
+			return err
+		}
+		if retired {
+			delete(annotations, SharedServiceUserAnnotation)
+		}
+		return nil
+	}
+
+	shared := sharedServiceUserName(application, access)
+	used := previous == shared
+	if !used {
+		var err error
+		used, err = utils.ServiceUserInUse(ctx, h.secrets, secret, map[string]string{
+			ServiceUserAnnotation: shared,
+			ProjectAnnotation:     h.projectName,
+			ServiceAnnotation:     serviceName,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to check if service user %s is in use: %w", shared, err)
+		}
+	}
+	if used {
+		logger.Infof("Migrating from shared service user %s to service user %s", shared, annotations[ServiceUserAnnotation])
+		annotations[SharedServiceUserAnnotation] = shared
+	}
+	return nil
+}
+
+// retireServiceUser deletes a service user when no secret other than the given one uses it
+func (h OpenSearchHandler) retireServiceUser(ctx context.Context, serviceUserName, projectName, serviceName string, secret *v1.Secret, logger log.FieldLogger) (bool, error) {
+	used, err := utils.ServiceUserInUse(ctx, h.secrets, secret, map[string]string{
+		ServiceUserAnnotation: serviceUserName,
+		ProjectAnnotation:     projectName,
+		ServiceAnnotation:     serviceName,
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to check if service user %s is in use: %w", serviceUserName, err)
+	}
+	if used {
+		return false, nil
+	}
+
+	err = h.deleteServiceUser(ctx, serviceUserName, projectName, serviceName, logger)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h OpenSearchHandler) deleteServiceUser(ctx context.Context, serviceUserName, projectName, serviceName string, logger log.FieldLogger) error {
+	err := h.removeACL(ctx, serviceUserName, projectName, serviceName)
+	if err != nil && !aiven.IsNotFound(err) {
+		return err
+	}
+	err = h.serviceuser.Delete(ctx, serviceUserName, projectName, serviceName, logger)
+	if err != nil {
+		if aiven.IsNotFound(err) {
+			logger.Infof("Service user %s does not exist", serviceUserName)
+			return nil
+		}
+		return err
+	}
+	logger.Infof("Deleted service user %s", serviceUserName)
+	return nil
+}
+
+// Cleanup deletes per-application service users when no other secret uses them. Shared service users are only deleted
+// once per-application users are enabled and no secret uses them.
+func (h OpenSearchHandler) Cleanup(ctx context.Context, secret *v1.Secret, logger *log.Entry) error {
+	annotations := secret.GetAnnotations()
+	applicationUser := annotations[ApplicationUserAnnotation] == "true"
+	// Secrets of applications not synchronized since per-application users were enabled hold shared users, which are
+	// retired when the last of them is deleted. Failed synchronizations clean up too, while the secret is still in use.
+	legacyDeleted := h.config.PerApplicationUsers && secret.GetDeletionTimestamp() != nil
+	if !applicationUser && !legacyDeleted {
+		return nil
+	}
+	serviceUserName, okUser := annotations[ServiceUserAnnotation]
+	projectName, okProject := annotations[ProjectAnnotation]
+	serviceName, okService := annotations[ServiceAnnotation]
+	if !applicationUser && !okUser {
+		return nil
+	}
+	if !okUser || !okProject || !okService {
+		return fmt.Errorf("missing service user, project or service annotation on secret %s in namespace %s, unable to delete service user",
+			secret.GetName(), secret.GetNamespace())
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"handler": "opensearch",
+		"project": projectName,
+		"service": serviceName,
+	})
+	if shared, ok := annotations[SharedServiceUserAnnotation]; ok {
+		_, err := h.retireServiceUser(ctx, shared, projectName, serviceName, secret, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	retired, err := h.retireServiceUser(ctx, serviceUserName, projectName, serviceName, secret, logger)
+	if err != nil {
+		return err
+	}
+	if !retired {
+		logger.Infof("Service user %s is used by other secrets, keeping it", serviceUserName)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aiven/aiven-go-client/v2"
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// Annotations on the AivenApplication, prefixed with the instance name, with comma separated key and channel patterns.
+// The Redis spec has no fields for them.
+const (
+	KeysAnnotation     = "redis.aiven.nais.io/keys"
+	ChannelsAnnotation = "redis.aiven.nais.io/channels"
+)
+
+// Config holds the operator level settings for the Redis handler
+type Config struct {
+	// ApplicationPatterns limits applications without key and channel annotations to keys and channels prefixed with
+	// their name, instead of all keys and channels. Existing service users are narrowed as well.
+	ApplicationPatterns bool
+	// UnrestrictedUsers allows service users without access control on Dragonfly services, where aivenator does not
+	// support access control. Such users can use all keys, channels and commands.
+	UnrestrictedUsers bool
+}
+
+// defaultPatterns gives access to all keys and channels, as service users had before patterns could be set. Operators
+// may limit applications to keys and channels prefixed with their name, so applications sharing an instance do not see
+// each other's data. Access to all keys and channels by default is deprecated, and counted in metrics until it is gone.
+func (c Config) defaultPatterns(application *aiven_nais_io_v1.AivenApplication) []string {
+	if c.ApplicationPatterns {
+		return []string{application.GetName() + ":*"}
+	}
+	return []string{"*"}
+}
+
+// patterns reads the patterns for the instance from the annotation, or falls back to the default
+func (c Config) patterns(application *aiven_nais_io_v1.AivenApplication, instanceName, annotation string) ([]string, error) {
+	key := patternsAnnotation(instanceName, annotation)
+	value, ok := application.GetAnnotations()[key]
+	if !ok {
+		return c.defaultPatterns(application), nil
+	}
+
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.ContainsAny(pattern, " \t\n") {
+			return nil, fmt.Errorf("pattern %q in annotation %s contains whitespace: %w", pattern, key, utils.UnrecoverableError)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("annotation %s has no patterns: %w", key, utils.UnrecoverableError)
+	}
+	return patterns, nil
+}
+
+// wildcardDefault tells whether the default patterns of the instance give access to all keys or channels
+func (c Config) wildcardDefault(application *aiven_nais_io_v1.AivenApplication, instanceName string) bool {
+	if c.ApplicationPatterns {
+		return false
+	}
+	for _, annotation := range []string{KeysAnnotation, ChannelsAnnotation} {
+		if _, ok := application.GetAnnotations()[patternsAnnotation(instanceName, annotation)]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func patternsAnnotation(instanceName, annotation string) string {
+	return fmt.Sprintf("%s.%s", keyName(instanceName, "-"), annotation)
+}
+
+// accessControl builds the access control for the service user of an instance
+func (h RedisHandler) accessControl(application *aiven_nais_io_v1.AivenApplication, spec *aiven_nais_io_v1.RedisSpec, logger log.FieldLogger) (*aiven.AccessControl, error) {
+	keys, err := h.config.patterns(application, spec.Instance, KeysAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := h.config.patterns(application, spec.Instance, ChannelsAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if h.config.wildcardDefault(application, spec.Instance) {
+		logger.Warnf("Service user for instance %s can use all keys and channels by default, which is deprecated; set %s and %s on the application",
+			spec.Instance, patternsAnnotation(spec.Instance, KeysAnnotation), patternsAnnotation(spec.Instance, ChannelsAnnotation))
+		metrics.RedisWildcardPatterns.With(prometheus.Labels{metrics.LabelNamespace: application.GetNamespace()}).Inc()
+	}
+	return &aiven.AccessControl{
+		RedisACLCategories: getRedisACLCategories(spec.Access),
+		RedisACLKeys:       keys,
+		RedisACLChannels:   channels,
+	}, nil
+}
+
+// Parts of the access control that may drift, used in metrics
+const (
+	driftCategories = "categories"
+	driftCommands   = "commands"
+	driftKeys       = "keys"
+	driftChannels   = "channels"
+)
+
+// accessControlDrift lists the parts of the access control of a service user that differ from the desired access control.
+// Categories are applied in order by Redis, so their order matters. Pattern order does not.
+func accessControlDrift(existing aiven.AccessControl, desired *aiven.AccessControl) []string {
+	var drift []string
+	if !slices.Equal(existing.RedisACLCategories, desired.RedisACLCategories) {
+		drift = append(drift, driftCategories)
+	}
+	if !samePatterns(existing.RedisACLCommands, desired.RedisACLCommands) {
+		drift = append(drift, driftCommands)
+	}
+	if !samePatterns(existing.RedisACLKeys, desired.RedisACLKeys) {
+		drift = append(drift, driftKeys)
+	}
+	if !samePatterns(existing.RedisACLChannels, desired.RedisACLChannels) {
+		drift = append(drift, driftChannels)
+	}
+	return drift
+}
+
+func samePatterns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
+
+// createServiceUser creates a service user with the access control, which Valkey services take in their own fields
+func (h RedisHandler) createServiceUser(ctx context.Context, flavour flavour, serviceUserName, serviceName string, accessControl *aiven.AccessControl, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	if flavour.serviceType == flavourValkey {
+		return h.valkey.Create(ctx, serviceUserName, h.projectName, serviceName, accessControl)
+	}
+	return h.serviceuser.Create(ctx, serviceUserName, h.projectName, serviceName, accessControl, logger)
+}
+
+// reconcileAccessControl updates the access control of an existing service user when it differs from the desired one,
+// such as after the category mapping or the patterns of the application changed. Every correction is counted.
+func (h RedisHandler) reconcileAccessControl(ctx context.Context, flavour flavour, aivenUser *aiven.ServiceUser, desired *aiven.AccessControl, serviceName string, logger log.FieldLogger) (*aiven.ServiceUser, error) {
+	existing := aivenUser.AccessControl
+	if flavour.serviceType == flavourValkey {
+		valkeyAccessControl, err := h.valkey.GetAccessControl(ctx, aivenUser.Username, h.projectName, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		existing = *valkeyAccessControl
+	}
+	drift := accessControlDrift(existing, desired)
+	if len(drift) == 0 {
+		return aivenUser, nil
+	}
+
+	logger.Infof("Access control of service user %s differs in %s, updating", aivenUser.Username, strings.Join(drift, ", "))
+	updated := aivenUser
+	var err error
+	if flavour.serviceType == flavourValkey {
+		err = h.valkey.UpdateAccessControl(ctx, aivenUser.Username, h.projectName, serviceName, desired)
+	} else {
+		updated, err = h.serviceuser.UpdateAccessControl(ctx, aivenUser.Username, h.projectName, serviceName, desired, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range drift {
+		metrics.RedisAccessControlCorrected.With(prometheus.Labels{
+			metrics.LabelPool:  h.projectName,
+			metrics.LabelDrift: part,
+		}).Inc()
+	}
+	return updated, nil
+}
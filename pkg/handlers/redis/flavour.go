@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"github.com/nais/aivenator/pkg/aiven/service"
+)
+
+// Service types speaking the Redis protocol, told apart by the component serving clients
+const (
+	flavourRedis     = service.ComponentRedis
+	flavourValkey    = service.ComponentValkey
+	flavourDragonfly = service.ComponentDragonfly
+)
+
+// envNames are the environment variables with the credentials of an instance, before the instance suffix
+type envNames struct {
+	user     string
+	password string
+	uri      string
+}
+
+var (
+	redisEnv  = envNames{user: RedisUser, password: RedisPassword, uri: RedisURI}
+	valkeyEnv = envNames{user: ValkeyUser, password: ValkeyPassword, uri: ValkeyURI}
+)
+
+type flavour struct {
+	serviceType string
+	address     string
+	// accessControl is false when aivenator does not support access control for the service type,
+	// so service users can not be limited to categories, keys and channels
+	accessControl bool
+	// env always includes the REDIS_ names, so applications keep working when moving from Redis
+	env []envNames
+}
+
+// detectFlavour looks for the component of each service type among the addresses of the service
+func detectFlavour(addresses *service.ServiceAddresses) flavour {
+	switch {
+	case addresses.Valkey != "":
+		return flavour{
+			serviceType:   flavourValkey,
+			address:       addresses.Valkey,
+			accessControl: true,
+			env:           []envNames{redisEnv, valkeyEnv},
+		}
+	case addresses.Dragonfly != "":
+		return flavour{
+			serviceType: flavourDragonfly,
+			address:     addresses.Dragonfly,
+			env:         []envNames{redisEnv},
+		}
+	default:
+		return flavour{
+			serviceType:   flavourRedis,
+			address:       addresses.Redis,
+			accessControl: true,
+			env:           []envNames{redisEnv},
+		}
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/service"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/aiven/valkey"
+	"github.com/nais/aivenator/pkg/annotations"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/policy"
 	"github.com/nais/aivenator/pkg/utils"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"regexp"
@@ -18,6 +23,8 @@ import (
 const (
 	ServiceUserAnnotation = "redis.aiven.nais.io/serviceUser"
 	ProjectAnnotation     = "redis.aiven.nais.io/project"
+	// Prefixed with the instance name, e.g. "cache.redis.aiven.nais.io/addressRoute"
+	AddressRouteAnnotation = "redis.aiven.nais.io/addressRoute"
 )
 
 // Environment variables
@@ -25,22 +32,34 @@ const (
 	RedisUser     = "REDIS_USERNAME"
 	RedisPassword = "REDIS_PASSWORD"
 	RedisURI      = "REDIS_URI"
+	// Also set for Valkey services
+	ValkeyUser     = "VALKEY_USERNAME"
+	ValkeyPassword = "VALKEY_PASSWORD"
+	ValkeyURI      = "VALKEY_URI"
 )
 
 var namePattern = regexp.MustCompile("[^a-z0-9]")
 
-func NewRedisHandler(ctx context.Context, aiven *aiven.Client, projectName string) RedisHandler {
+func NewRedisHandler(ctx context.Context, aiven *aiven.Client, projectName string, addressRoutes service.AddressRoutes, names service.NameResolver, config Config, authorizer policy.Authorizer) RedisHandler {
 	return RedisHandler{
 		serviceuser: serviceuser.NewManager(ctx, aiven.ServiceUsers),
-		service:     service.NewManager(aiven.Services),
+		valkey:      valkey.NewManager(aiven),
+		service:     service.NewManager(aiven.Services, addressRoutes),
+		names:       names,
+		authorizer:  authorizer,
 		projectName: projectName,
+		config:      config,
 	}
 }
 
 type RedisHandler struct {
 	serviceuser serviceuser.ServiceUserManager
+	valkey      valkey.ServiceUserManager
 	service     service.ServiceManager
+	names       service.NameResolver
+	authorizer  policy.Authorizer
 	projectName string
+	config      Config
 }
 
 func (h RedisHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.AivenApplication, secret *v1.Secret, logger log.FieldLogger) error {
@@ -50,38 +69,76 @@ func (h RedisHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.A
 	}
 
 	for _, spec := range application.Spec.Redis {
-		serviceName := fmt.Sprintf("redis-%s-%s", application.GetNamespace(), spec.Instance)
+		serviceName, err := h.names.ResolveServiceName(service.Redis, application, spec.Instance)
+		if err != nil {
+			utils.LocalFail("ResolveServiceName", application, err, logger)
+			return err
+		}
 
 		logger = logger.WithFields(log.Fields{
 			"project": h.projectName,
 			"service": serviceName,
 		})
 
+		err = policy.Check(h.authorizer, application, policy.Redis, serviceName, spec.Access, logger)
+		if err != nil {
+			return err
+		}
+
 		addresses, err := h.service.GetServiceAddresses(ctx, h.projectName, serviceName)
 		if err != nil {
 			return utils.AivenFail("GetService", application, err, true, logger)
 		}
 
-		serviceUserName := fmt.Sprintf("%s%s", application.GetName(), utils.SelectSuffix(spec.Access))
+		serviceUserName, err := serviceUserName(application, spec, serviceName)
+		if err != nil {
+			utils.LocalFail("ServiceUserName", application, err, logger)
+			return err
+		}
+
+		flavour := detectFlavour(addresses)
+		annotations.SetAddressRoute(secret, fmt.Sprintf("%s.%s", keyName(spec.Instance, "-"), AddressRouteAnnotation), addresses.Route(flavour.serviceType))
+		var accessControl *aiven.AccessControl
+		if flavour.accessControl {
+			accessControl, err = h.accessControl(application, spec, logger)
+			if err != nil {
+				utils.LocalFail("AccessControl", application, err, logger)
+				return err
+			}
+		} else if !h.config.UnrestrictedUsers {
+			err = fmt.Errorf("access control of %s service users is not supported, and unrestricted users are not allowed: %w", flavour.serviceType, utils.UnrecoverableError)
+			utils.LocalFail("AccessControl", application, err, logger)
+			return err
+		}
 
 		aivenUser, err := h.serviceuser.Get(ctx, serviceUserName, h.projectName, serviceName, logger)
 		if err != nil {
 			if aiven.IsNotFound(err) {
-				accessControl := &aiven.AccessControl{
-					RedisACLCategories: getRedisACLCategories(spec.Access),
-					RedisACLKeys:       []string{"*"},
-					RedisACLChannels:   []string{"*"},
+				if accessControl == nil {
+					logger.Warnf("Creating %s service user %s without access control", flavour.serviceType, serviceUserName)
 				}
-				aivenUser, err = h.serviceuser.Create(ctx, serviceUserName, h.projectName, serviceName, accessControl, logger)
+				aivenUser, err = h.createServiceUser(ctx, flavour, serviceUserName, serviceName, accessControl, logger)
 				if err != nil {
 					return utils.AivenFail("CreateServiceUser", application, err, false, logger)
 				}
 			} else {
 				return utils.AivenFail("GetServiceUser", application, err, false, logger)
 			}
+		} else if accessControl != nil {
+			aivenUser, err = h.reconcileAccessControl(ctx, flavour, aivenUser, accessControl, serviceName, logger)
+			if err != nil {
+				return utils.AivenFail("UpdateAccessControl", application, err, false, logger)
+			}
+		}
+
+		if accessControl == nil {
+			metrics.RedisUnrestrictedServiceUsers.With(prometheus.Labels{metrics.LabelNamespace: application.GetNamespace()}).Inc()
 		}
 
 		serviceUserAnnotationKey := fmt.Sprintf("%s.%s", keyName(spec.Instance, "-"), ServiceUserAnnotation)
+		if previous, ok := secret.GetAnnotations()[serviceUserAnnotationKey]; ok && previous != aivenUser.Username {
+			logger.Warnf("Service user of instance %s changed from %s to %s, the old service user is not deleted", spec.Instance, previous, aivenUser.Username)
+		}
 
 		secret.SetAnnotations(utils.MergeStringMap(secret.GetAnnotations(), map[string]string{
 			serviceUserAnnotationKey: aivenUser.Username,
@@ -90,16 +147,38 @@ func (h RedisHandler) Apply(ctx context.Context, application *aiven_nais_io_v1.A
 		logger.Infof("Fetched service user %s", aivenUser.Username)
 
 		envVarSuffix := envVarName(spec.Instance)
-		secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
-			fmt.Sprintf("%s_%s", RedisUser, envVarSuffix):     aivenUser.Username,
-			fmt.Sprintf("%s_%s", RedisPassword, envVarSuffix): aivenUser.Password,
-			fmt.Sprintf("%s_%s", RedisURI, envVarSuffix):      addresses.Redis,
-		})
+		for _, env := range flavour.env {
+			secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
+				fmt.Sprintf("%s_%s", env.user, envVarSuffix):     aivenUser.Username,
+				fmt.Sprintf("%s_%s", env.password, envVarSuffix): aivenUser.Password,
+				fmt.Sprintf("%s_%s", env.uri, envVarSuffix):      flavour.address,
+			})
+		}
 	}
 
 	return nil
 }
 
+// serviceUserName is unique within the namespace. Services named by the default template are dedicated to their
+// namespace; services named by a configured template or mapping may be shared with other namespaces, so the namespace
+// is added to the name of their users. The name of a user only changes along with the service it belongs to, so
+// changing templates or mappings moves applications to new users on other services and leaves their old users behind.
+func serviceUserName(application *aiven_nais_io_v1.AivenApplication, spec *aiven_nais_io_v1.RedisSpec, serviceName string) (string, error) {
+	defaultServiceName, err := service.DefaultServiceName(service.Redis, application, spec.Instance)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s%s", application.GetName(), utils.SelectSuffix(spec.Access))
+	if serviceName == defaultServiceName {
+		return name, nil
+	}
+	name = fmt.Sprintf("%s-%s", application.GetNamespace(), name)
+	if len(name) > utils.MaxServiceUserNameLength {
+		return "", fmt.Errorf("service user name %s is longer than %d characters: %w", name, utils.MaxServiceUserNameLength, utils.UnrecoverableError)
+	}
+	return name, nil
+}
+
 func keyName(instanceName, replacement string) string {
 	return namePattern.ReplaceAllString(instanceName, replacement)
 }
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"github.com/nais/aivenator/pkg/aiven/serviceuser"
+	"github.com/nais/aivenator/pkg/aiven/valkey"
 	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"testing"
@@ -10,9 +11,13 @@ import (
 
 	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/service"
+	"github.com/nais/aivenator/pkg/metrics"
+	"github.com/nais/aivenator/pkg/policy"
+	"github.com/nais/aivenator/pkg/utils"
 	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
@@ -62,8 +67,19 @@ var testInstances = []testData{
 	},
 }
 
+var defaultPatterns = []string{"*"}
+
+func defaultAccessControl(data testData) aiven.AccessControl {
+	return aiven.AccessControl{
+		RedisACLCategories: getRedisACLCategories(data.access),
+		RedisACLKeys:       defaultPatterns,
+		RedisACLChannels:   defaultPatterns,
+	}
+}
+
 type mockContainer struct {
 	serviceUserManager *serviceuser.MockServiceUserManager
+	valkeyManager      *valkey.MockServiceUserManager
 	serviceManager     *service.MockServiceManager
 }
 
@@ -97,11 +113,15 @@ var _ = Describe("redis.Handler", func() {
 		secret = v1.Secret{}
 		mocks = mockContainer{
 			serviceUserManager: serviceuser.NewMockServiceUserManager(GinkgoT()),
+			valkeyManager:      valkey.NewMockServiceUserManager(GinkgoT()),
 			serviceManager:     service.NewMockServiceManager(GinkgoT()),
 		}
 		redisHandler = RedisHandler{
 			serviceuser: mocks.serviceUserManager,
+			valkey:      mocks.valkeyManager,
 			service:     mocks.serviceManager,
+			names:       service.NewDefaultNameResolver(),
+			authorizer:  policy.AllowAll{},
 			projectName: projectName,
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
@@ -207,8 +227,9 @@ var _ = Describe("redis.Handler", func() {
 				defaultServiceManagerMock(data)
 				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
 					Return(&aiven.ServiceUser{
-						Username: data.username,
-						Password: servicePassword,
+						Username:      data.username,
+						Password:      servicePassword,
+						AccessControl: defaultAccessControl(data),
 					}, nil)
 			})
 
@@ -216,6 +237,15 @@ var _ = Describe("redis.Handler", func() {
 				err := redisHandler.Apply(ctx, &application, &secret, logger)
 				assertHappy(&secret, err)
 			})
+
+			It("counts the deprecated access to all keys and channels", func() {
+				wildcard := metrics.RedisWildcardPatterns.WithLabelValues(namespace)
+				before := testutil.ToFloat64(wildcard)
+
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(testutil.ToFloat64(wildcard)).To(Equal(before + 1))
+			})
 		})
 
 		Context("and the service user doesn't exist", func() {
@@ -228,8 +258,8 @@ var _ = Describe("redis.Handler", func() {
 					})
 				accessControl := &aiven.AccessControl{
 					RedisACLCategories: getRedisACLCategories(data.access),
-					RedisACLKeys:       []string{"*"},
-					RedisACLChannels:   []string{"*"},
+					RedisACLKeys:       defaultPatterns,
+					RedisACLChannels:   defaultPatterns,
 				}
 				mocks.serviceUserManager.On("Create", mock.Anything, data.username, projectName, data.serviceName, accessControl, mock.Anything).
 					Return(&aiven.ServiceUser{
@@ -245,6 +275,417 @@ var _ = Describe("redis.Handler", func() {
 		})
 	})
 
+	When("it receives a spec with key and channel patterns", func() {
+		data := testInstances[0]
+		keys := []string{"test-app:*", "shared:*"}
+		channels := []string{"events"}
+
+		BeforeEach(func() {
+			application = applicationBuilder.
+				WithAnnotation("my-instance1.redis.aiven.nais.io/keys", "test-app:*, shared:*").
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			application.GetAnnotations()["my-instance1.redis.aiven.nais.io/channels"] = "events"
+			defaultServiceManagerMock(data)
+		})
+
+		Context("and the service user doesn't exist", func() {
+			BeforeEach(func() {
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(nil, aiven.Error{
+						Message: "Service user does not exist",
+						Status:  404,
+					})
+				accessControl := &aiven.AccessControl{
+					RedisACLCategories: getRedisACLCategories(data.access),
+					RedisACLKeys:       keys,
+					RedisACLChannels:   channels,
+				}
+				mocks.serviceUserManager.On("Create", mock.Anything, data.username, projectName, data.serviceName, accessControl, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username: data.username,
+						Password: servicePassword,
+					}, nil)
+			})
+
+			It("creates the user with the patterns", func() {
+				wildcard := metrics.RedisWildcardPatterns.WithLabelValues(namespace)
+				before := testutil.ToFloat64(wildcard)
+
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(data.usernameKey, data.username))
+				Expect(testutil.ToFloat64(wildcard)).To(Equal(before))
+			})
+		})
+
+		Context("and the service user has other patterns", func() {
+			BeforeEach(func() {
+				existing := aiven.AccessControl{
+					RedisACLCategories: getRedisACLCategories(data.access),
+					RedisACLKeys:       []string{"*"},
+					RedisACLChannels:   []string{"*"},
+				}
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username:      data.username,
+						Password:      servicePassword,
+						AccessControl: existing,
+					}, nil)
+				updated := existing
+				updated.RedisACLKeys = keys
+				updated.RedisACLChannels = channels
+				mocks.serviceUserManager.On("UpdateAccessControl", mock.Anything, data.username, projectName, data.serviceName, &updated, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username:      data.username,
+						Password:      servicePassword,
+						AccessControl: updated,
+					}, nil)
+			})
+
+			It("updates the patterns of the user", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(data.passwordKey, servicePassword))
+			})
+		})
+
+		Context("and the service user has the same patterns in another order", func() {
+			BeforeEach(func() {
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username: data.username,
+						Password: servicePassword,
+						AccessControl: aiven.AccessControl{
+							RedisACLCategories: getRedisACLCategories(data.access),
+							RedisACLKeys:       []string{"shared:*", "test-app:*"},
+							RedisACLChannels:   channels,
+						},
+					}, nil)
+			})
+
+			It("leaves the user as it is", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				mocks.serviceUserManager.AssertNotCalled(GinkgoT(), "UpdateAccessControl", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("and an annotation has no patterns", func() {
+			BeforeEach(func() {
+				application.GetAnnotations()["my-instance1.redis.aiven.nais.io/keys"] = " , "
+			})
+
+			It("sets the local fail condition", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(MatchError(utils.UnrecoverableError))
+				Expect(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure)).ToNot(BeNil())
+			})
+		})
+	})
+
+	When("the access control of an existing user differs", func() {
+		data := testInstances[0]
+
+		BeforeEach(func() {
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			defaultServiceManagerMock(data)
+			existing := defaultAccessControl(data)
+			existing.RedisACLCategories = []string{"-@all", "+@connection", "+@scripting", "+@pubsub", "+@read"}
+			existing.RedisACLCommands = []string{"+flushall"}
+			mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+				Return(&aiven.ServiceUser{
+					Username:      data.username,
+					Password:      servicePassword,
+					AccessControl: existing,
+				}, nil)
+			desired := defaultAccessControl(data)
+			mocks.serviceUserManager.On("UpdateAccessControl", mock.Anything, data.username, projectName, data.serviceName, &desired, mock.Anything).
+				Return(&aiven.ServiceUser{
+					Username:      data.username,
+					Password:      servicePassword,
+					AccessControl: desired,
+				}, nil)
+		})
+
+		It("updates the user and counts the correction", func() {
+			categories := metrics.RedisAccessControlCorrected.WithLabelValues(projectName, driftCategories)
+			commands := metrics.RedisAccessControlCorrected.WithLabelValues(projectName, driftCommands)
+			keys := metrics.RedisAccessControlCorrected.WithLabelValues(projectName, driftKeys)
+			before := []float64{testutil.ToFloat64(categories), testutil.ToFloat64(commands), testutil.ToFloat64(keys)}
+
+			err := redisHandler.Apply(ctx, &application, &secret, logger)
+			Expect(err).To(Succeed())
+			Expect(testutil.ToFloat64(categories)).To(Equal(before[0] + 1))
+			Expect(testutil.ToFloat64(commands)).To(Equal(before[1] + 1))
+			Expect(testutil.ToFloat64(keys)).To(Equal(before[2]))
+		})
+	})
+
+	When("the instance resolves to a shared service", func() {
+		data := testData{
+			instanceName: "sessions",
+			serviceName:  "redis-shared-sessions",
+			serviceURI:   "rediss://sessions.example.com:23456",
+			access:       "read",
+			username:     "team-a-test-app-r",
+		}
+
+		BeforeEach(func() {
+			names, err := service.NewTemplateNameResolver(service.NameConfig{
+				Mappings: map[string]string{"redis/team-a/sessions": data.serviceName},
+			})
+			Expect(err).To(Succeed())
+			redisHandler.names = names
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			defaultServiceManagerMock(data)
+			mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+				Return(&aiven.ServiceUser{
+					Username:      data.username,
+					Password:      servicePassword,
+					AccessControl: defaultAccessControl(data),
+				}, nil)
+		})
+
+		It("uses a service user with the namespace in its name", func() {
+			err := redisHandler.Apply(ctx, &application, &secret, logger)
+			Expect(err).To(Succeed())
+			Expect(secret.GetAnnotations()).To(HaveKeyWithValue("sessions.redis.aiven.nais.io/serviceUser", data.username))
+			Expect(secret.StringData).To(HaveKeyWithValue("REDIS_URI_SESSIONS", data.serviceURI))
+		})
+	})
+
+	When("a mapping resolves the instance to the service dedicated to the namespace", func() {
+		data := testInstances[0]
+
+		BeforeEach(func() {
+			names, err := service.NewTemplateNameResolver(service.NameConfig{
+				Templates: map[string]string{service.Redis: "redis-tenant-{{ .Instance }}"},
+				Mappings:  map[string]string{"redis/team-a/my-instance1": data.serviceName},
+			})
+			Expect(err).To(Succeed())
+			redisHandler.names = names
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			defaultServiceManagerMock(data)
+			mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+				Return(&aiven.ServiceUser{
+					Username:      data.username,
+					Password:      servicePassword,
+					AccessControl: defaultAccessControl(data),
+				}, nil)
+		})
+
+		It("keeps the service user without the namespace in its name", func() {
+			err := redisHandler.Apply(ctx, &application, &secret, logger)
+			Expect(err).To(Succeed())
+			Expect(secret.GetAnnotations()).To(HaveKeyWithValue(data.serviceUserAnnotationKey, data.username))
+		})
+	})
+
+	When("the instance is a Valkey service", func() {
+		data := testInstances[0]
+		valkeyURI := "rediss://valkey.example.com:23456"
+
+		BeforeEach(func() {
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			mocks.serviceManager.On("GetServiceAddresses", mock.Anything, projectName, data.serviceName).
+				Return(&service.ServiceAddresses{
+					Valkey: valkeyURI,
+				}, nil)
+		})
+
+		Context("and the service user doesn't exist", func() {
+			BeforeEach(func() {
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(nil, aiven.Error{
+						Message: "Service user does not exist",
+						Status:  404,
+					})
+				accessControl := defaultAccessControl(data)
+				mocks.valkeyManager.On("Create", mock.Anything, data.username, projectName, data.serviceName, &accessControl).
+					Return(&aiven.ServiceUser{
+						Username: data.username,
+						Password: servicePassword,
+					}, nil)
+			})
+
+			It("creates the user with access control and sets both Redis and Valkey variables", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(data.usernameKey, data.username))
+				Expect(secret.StringData).To(HaveKeyWithValue(data.uriKey, valkeyURI))
+				Expect(secret.StringData).To(HaveKeyWithValue("VALKEY_USERNAME_MY_INSTANCE1", data.username))
+				Expect(secret.StringData).To(HaveKeyWithValue("VALKEY_PASSWORD_MY_INSTANCE1", servicePassword))
+				Expect(secret.StringData).To(HaveKeyWithValue("VALKEY_URI_MY_INSTANCE1", valkeyURI))
+			})
+		})
+
+		Context("and the service user already exists", func() {
+			BeforeEach(func() {
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username: data.username,
+						Password: servicePassword,
+					}, nil)
+			})
+
+			It("leaves matching access control as it is", func() {
+				accessControl := defaultAccessControl(data)
+				mocks.valkeyManager.On("GetAccessControl", mock.Anything, data.username, projectName, data.serviceName).
+					Return(&accessControl, nil)
+
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				mocks.valkeyManager.AssertNotCalled(GinkgoT(), "UpdateAccessControl", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			It("updates other access control", func() {
+				accessControl := defaultAccessControl(data)
+				mocks.valkeyManager.On("GetAccessControl", mock.Anything, data.username, projectName, data.serviceName).
+					Return(&aiven.AccessControl{RedisACLCategories: accessControl.RedisACLCategories}, nil)
+				mocks.valkeyManager.On("UpdateAccessControl", mock.Anything, data.username, projectName, data.serviceName, &accessControl).
+					Return(nil)
+
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(data.usernameKey, data.username))
+			})
+		})
+	})
+
+	When("the instance is a Dragonfly service", func() {
+		data := testInstances[0]
+		dragonflyURI := "rediss://dragonfly.example.com:23456"
+
+		BeforeEach(func() {
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			mocks.serviceManager.On("GetServiceAddresses", mock.Anything, projectName, data.serviceName).
+				Return(&service.ServiceAddresses{
+					Dragonfly: dragonflyURI,
+				}, nil)
+		})
+
+		Context("and unrestricted users are not allowed", func() {
+			It("sets the local fail condition", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(MatchError(utils.UnrecoverableError))
+				Expect(application.Status.GetConditionOfType(aiven_nais_io_v1.AivenApplicationLocalFailure)).ToNot(BeNil())
+			})
+		})
+
+		Context("and unrestricted users are allowed", func() {
+			BeforeEach(func() {
+				redisHandler.config.UnrestrictedUsers = true
+				mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+					Return(&aiven.ServiceUser{
+						Username: data.username,
+						Password: servicePassword,
+					}, nil)
+			})
+
+			It("sets the Redis variables only", func() {
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(secret.StringData).To(HaveKeyWithValue(data.uriKey, dragonflyURI))
+				Expect(secret.StringData).To(HaveLen(3))
+				mocks.serviceUserManager.AssertNotCalled(GinkgoT(), "UpdateAccessControl", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			It("counts the unrestricted user", func() {
+				unrestricted := metrics.RedisUnrestrictedServiceUsers.WithLabelValues(namespace)
+				before := testutil.ToFloat64(unrestricted)
+
+				err := redisHandler.Apply(ctx, &application, &secret, logger)
+				Expect(err).To(Succeed())
+				Expect(testutil.ToFloat64(unrestricted)).To(Equal(before + 1))
+			})
+		})
+	})
+
+	When("application patterns are configured", func() {
+		data := testInstances[0]
+
+		BeforeEach(func() {
+			redisHandler.config.ApplicationPatterns = true
+			application = applicationBuilder.
+				WithSpec(aiven_nais_io_v1.AivenApplicationSpec{
+					Redis: []*aiven_nais_io_v1.RedisSpec{
+						{
+							Instance: data.instanceName,
+							Access:   data.access,
+						},
+					}}).
+				Build()
+			defaultServiceManagerMock(data)
+			mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
+				Return(nil, aiven.Error{
+					Message: "Service user does not exist",
+					Status:  404,
+				})
+			accessControl := &aiven.AccessControl{
+				RedisACLCategories: getRedisACLCategories(data.access),
+				RedisACLKeys:       []string{appName + ":*"},
+				RedisACLChannels:   []string{appName + ":*"},
+			}
+			mocks.serviceUserManager.On("Create", mock.Anything, data.username, projectName, data.serviceName, accessControl, mock.Anything).
+				Return(&aiven.ServiceUser{
+					Username: data.username,
+					Password: servicePassword,
+				}, nil)
+		})
+
+		It("limits applications without annotations to keys and channels prefixed with their name", func() {
+			err := redisHandler.Apply(ctx, &application, &secret, logger)
+			Expect(err).To(Succeed())
+		})
+	})
+
 	When("it receives a spec with multiple instances", func() {
 		BeforeEach(func() {
 			var specs []*aiven_nais_io_v1.RedisSpec
@@ -278,8 +719,9 @@ var _ = Describe("redis.Handler", func() {
 					defaultServiceManagerMock(data)
 					mocks.serviceUserManager.On("Get", mock.Anything, data.username, projectName, data.serviceName, mock.Anything).
 						Return(&aiven.ServiceUser{
-							Username: data.username,
-							Password: servicePassword,
+							Username:      data.username,
+							Password:      servicePassword,
+							AccessControl: defaultAccessControl(data),
 						}, nil)
 				}
 			})
@@ -303,8 +745,8 @@ var _ = Describe("redis.Handler", func() {
 						})
 					accessControl := &aiven.AccessControl{
 						RedisACLCategories: getRedisACLCategories(data.access),
-						RedisACLKeys:       []string{"*"},
-						RedisACLChannels:   []string{"*"},
+						RedisACLKeys:       defaultPatterns,
+						RedisACLChannels:   defaultPatterns,
 					}
 					mocks.serviceUserManager.On("Create", mock.Anything, data.username, projectName, data.serviceName, accessControl, mock.Anything).
 						Return(&aiven.ServiceUser{
@@ -3,7 +3,6 @@ package secret
 import (
 	"context"
 	"fmt"
-	"github.com/aiven/aiven-go-client/v2"
 	"github.com/nais/aivenator/pkg/aiven/project"
 	"strconv"
 	"time"
@@ -24,14 +23,23 @@ const (
 	AivenCAKey            = "AIVEN_CA"
 )
 
+// Annotations used to follow the project CA across rotations
+const (
+	CAFingerprintAnnotation   = "aivenator.aiven.nais.io/ca-fingerprint"
+	PreviousCAAnnotation      = "aivenator.aiven.nais.io/previous-ca"
+	PreviousCAUntilAnnotation = "aivenator.aiven.nais.io/previous-ca-until"
+)
+
 type Handler struct {
 	project     project.ProjectManager
+	caBundler   project.CABundler
 	projectName string
 }
 
-func NewHandler(aiven *aiven.Client, projectName string) Handler {
+func NewHandler(projectManager project.ProjectManager, projectName string, caBundler project.CABundler) Handler {
 	return Handler{
-		project:     project.NewManager(aiven.CA),
+		project:     projectManager,
+		caBundler:   caBundler,
 		projectName: projectName,
 	}
 }
@@ -47,15 +55,23 @@ func (s Handler) Apply(ctx context.Context, application *aiven_nais_io_v1.AivenA
 	}
 
 	updateObjectMeta(application, &secret.ObjectMeta)
+	// Renewals are done by this synchronization, handlers needing a later one set the annotation again
+	delete(secret.GetAnnotations(), constants.AivenatorRenewAfterAnnotation)
 
 	projectCa, err := s.project.GetCA(ctx, s.projectName)
 	if err != nil {
 		return fmt.Errorf("unable to get project CA: %w", err)
 	}
 
+	caBundle := s.caBundler.Bundle(s.projectName, projectCa, string(secret.Data[AivenCAKey]), project.CAAnnotations{
+		Fingerprint:   CAFingerprintAnnotation,
+		Previous:      PreviousCAAnnotation,
+		PreviousUntil: PreviousCAUntilAnnotation,
+	}, secret, logger)
+
 	secret.StringData = utils.MergeStringMap(secret.StringData, map[string]string{
 		AivenSecretUpdatedKey: time.Now().Format(time.RFC3339),
-		AivenCAKey:            projectCa,
+		AivenCAKey:            caBundle,
 	})
 
 	return nil
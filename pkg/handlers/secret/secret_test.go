@@ -52,8 +52,9 @@ var _ = Describe("secret.Handler", func() {
 		mockProjects = project.NewMockProjectManager(GinkgoT())
 		mockProjects.On("GetCA", mock.Anything, projectName).Return(projectCA, nil).Maybe()
 		handler = Handler{
-			mockProjects,
-			projectName,
+			project:     mockProjects,
+			caBundler:   project.NewCABundler(project.CAConfig{}, nil),
+			projectName: projectName,
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	})
@@ -92,6 +93,20 @@ var _ = Describe("secret.Handler", func() {
 					Expect(a.secret.GetName()).To(Equal(a.application.Spec.SecretName))
 				},
 			}),
+		Entry("a secret marked for renewal without Kafka",
+			args{
+				application: exampleAivenApplication,
+				secret: corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        secretName,
+						Namespace:   namespace,
+						Annotations: map[string]string{constants.AivenatorRenewAfterAnnotation: time.Now().Add(-time.Minute).Format(time.RFC3339)},
+					},
+				},
+				assert: func(a args) {
+					Expect(a.secret.Annotations).ShouldNot(HaveKey(constants.AivenatorRenewAfterAnnotation))
+				},
+			}),
 		Entry("a pre-existing secret",
 			args{
 				application: exampleAivenApplication,
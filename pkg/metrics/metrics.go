@@ -21,6 +21,9 @@ const (
 	LabelSecretState        = "state"
 	LabelUserNameConvention = "username_convention"
 	LabelHandler            = "handler"
+	LabelResult             = "result"
+	LabelFingerprint        = "fingerprint"
+	LabelDrift              = "drift"
 )
 
 type Reason string
@@ -29,6 +32,7 @@ const (
 	HashChanged           Reason = "HashChanged"
 	MissingSecret         Reason = "MissingSecret"
 	MissingOwnerReference Reason = "MissingOwnerReference"
+	RenewalDue            Reason = "RenewalDue"
 )
 
 func (r Reason) String() string {
@@ -86,6 +90,66 @@ var (
 		Help:      "total count of service users",
 	}, []string{LabelPool, LabelUserNameConvention})
 
+	KafkaCertificatesReissued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "kafka_certificates_reissued",
+		Namespace: Namespace,
+		Help:      "number of service users replaced because their certificate was about to expire",
+	}, []string{LabelPool})
+
+	KafkaServiceUserMigrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "kafka_service_user_migrations",
+		Namespace: Namespace,
+		Help:      "number of service users with legacy names migrated to the current naming convention, or deferred by the rate limit",
+	}, []string{LabelPool, LabelUserNameConvention, LabelResult})
+
+	OpenSearchACLDriftCorrected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "opensearch_acl_drift_corrected",
+		Namespace: Namespace,
+		Help:      "number of OpenSearch ACL entries of existing service users corrected because they differed from the desired entry",
+	}, []string{LabelPool, LabelDrift})
+
+	RedisAccessControlCorrected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "redis_access_control_corrected",
+		Namespace: Namespace,
+		Help:      "number of corrections to the access control of existing redis service users, by the part that differed from the desired access control",
+	}, []string{LabelPool, LabelDrift})
+
+	RedisWildcardPatterns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "redis_wildcard_patterns",
+		Namespace: Namespace,
+		Help:      "number of redis service users synchronized with the deprecated default access to all keys and channels",
+	}, []string{LabelNamespace})
+
+	RedisUnrestrictedServiceUsers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "redis_unrestricted_service_users",
+		Namespace: Namespace,
+		Help:      "number of redis service users synchronized without access control, on services where aivenator does not support it",
+	}, []string{LabelNamespace})
+
+	KafkaCertificateEarliestExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "kafka_certificate_earliest_expiry_timestamp_seconds",
+		Namespace: Namespace,
+		Help:      "earliest expiry of kafka service user certificates in managed secrets",
+	}, []string{LabelNamespace})
+
+	ProjectCACacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "project_ca_cache_requests",
+		Namespace: Namespace,
+		Help:      "number of project CA lookups, by cache result",
+	}, []string{LabelPool, LabelResult})
+
+	ProjectCAChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "project_ca_changes",
+		Namespace: Namespace,
+		Help:      "number of times a project CA was seen changing",
+	}, []string{LabelPool})
+
+	ProjectCAInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "project_ca_info",
+		Namespace: Namespace,
+		Help:      "fingerprint of the current CA of each project, always 1",
+	}, []string{LabelPool, LabelFingerprint})
+
 	AivenLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:      "aiven_latency",
 		Namespace: Namespace,
@@ -165,5 +229,15 @@ func Register(registry prometheus.Registerer) {
 		SecretsManaged,
 		ServiceUsersCount,
 		ProcessingReason,
+		KafkaCertificatesReissued,
+		KafkaServiceUserMigrations,
+		KafkaCertificateEarliestExpiry,
+		OpenSearchACLDriftCorrected,
+		RedisAccessControlCorrected,
+		RedisWildcardPatterns,
+		RedisUnrestrictedServiceUsers,
+		ProjectCACacheRequests,
+		ProjectCAChanges,
+		ProjectCAInfo,
 	)
 }
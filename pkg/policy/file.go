@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// FileAuthorizer reads the access policy from a YAML file, typically a mounted ConfigMap.
+// The file is read again when it changes, so the policy can be updated without restarting.
+type FileAuthorizer struct {
+	path    string
+	lock    sync.Mutex
+	policy  Policy
+	modTime time.Time
+	size    int64
+}
+
+func NewFileAuthorizer(path string) *FileAuthorizer {
+	return &FileAuthorizer{path: path}
+}
+
+// Load reads the policy if the file changed since it was last read
+func (a *FileAuthorizer) Load() (Policy, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("unable to read access policy: %w", err)
+	}
+	if info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return a.policy, nil
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("unable to read access policy: %w", err)
+	}
+	var policy Policy
+	err = yaml.UnmarshalStrict(data, &policy)
+	if err != nil {
+		return Policy{}, fmt.Errorf("unable to parse access policy %s: %w", a.path, err)
+	}
+	err = policy.Validate()
+	if err != nil {
+		return Policy{}, fmt.Errorf("invalid access policy %s: %w", a.path, err)
+	}
+
+	a.policy = policy
+	a.modTime = info.ModTime()
+	a.size = info.Size()
+	return policy, nil
+}
+
+// Authorize denies everything while the policy can not be read, the error is recoverable so the request is retried
+func (a *FileAuthorizer) Authorize(namespace, service, instance, access string) error {
+	policy, err := a.Load()
+	if err != nil {
+		return err
+	}
+	return policy.Authorize(namespace, service, instance, access)
+}
@@ -0,0 +1,212 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+// Services the access policy has rules for
+const (
+	Kafka        = "kafka"
+	KafkaConnect = "kafkaconnect"
+	OpenSearch   = "opensearch"
+	Redis        = "redis"
+	InfluxDB     = "influxdb"
+)
+
+var services = []string{Kafka, KafkaConnect, OpenSearch, Redis, InfluxDB}
+
+// accessLevelServices are the services where applications ask for an access level
+var accessLevelServices = []string{OpenSearch, Redis}
+
+// namespacePlaceholder in an instance pattern is replaced by the namespace of the application
+const namespacePlaceholder = "{namespace}"
+
+// Access levels applications ask for
+var accessLevels = []string{"read", "write", "readwrite", "admin"}
+
+// Policy decides which instances applications in a namespace may get credentials for.
+// In NAIS, a namespace belongs to a single team, so rules for teams are written as rules for their namespaces.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule allows namespaces to use instances of a service, up to an access level.
+// Services without rules are not restricted, once a service has a rule, only what the rules allow is permitted.
+type Rule struct {
+	// Namespaces are shell patterns matching namespace names
+	Namespaces []string `json:"namespaces"`
+	// Service is one of kafka, kafkaconnect, opensearch, redis and influxdb
+	Service string `json:"service"`
+	// Instances are shell patterns matching service names in Aiven, or pool names for Kafka.
+	// {namespace} is replaced by the namespace of the application.
+	Instances []string `json:"instances"`
+	// MaxAccess is the highest access level allowed, empty for no limit. Only opensearch and redis have access levels.
+	// readwrite also allows read and write, admin allows everything.
+	MaxAccess string `json:"maxAccess,omitempty"`
+}
+
+// Authorizer is consulted by the handlers before provisioning credentials
+type Authorizer interface {
+	// Authorize returns a DeniedError when the namespace may not use the instance with the access level.
+	// The access level is ignored for services without access levels.
+	Authorize(namespace, service, instance, access string) error
+}
+
+// AllowAll is used when no access policy is configured
+type AllowAll struct{}
+
+func (AllowAll) Authorize(_, _, _, _ string) error {
+	return nil
+}
+
+// DeniedError is returned when the access policy does not allow a request.
+// Denials are unrecoverable, the application is not retried until it is synchronized again.
+type DeniedError struct {
+	Namespace string
+	Service   string
+	Instance  string
+	Access    string
+	// MaxAccess is set when the instance is allowed, but not with the requested access level
+	MaxAccess string
+}
+
+func (e DeniedError) Error() string {
+	if e.MaxAccess != "" {
+		return fmt.Sprintf("namespace %s is not allowed %s access to %s instance %s, at most %s", e.Namespace, e.Access, e.Service, e.Instance, e.MaxAccess)
+	}
+	return fmt.Sprintf("namespace %s is not allowed to use %s instance %s", e.Namespace, e.Service, e.Instance)
+}
+
+func (e DeniedError) Unwrap() error {
+	return utils.UnrecoverableError
+}
+
+func (p Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if !contains(services, rule.Service) {
+			return fmt.Errorf("rule %d: unknown service %q, must be one of %s", i, rule.Service, strings.Join(services, ", "))
+		}
+		if len(rule.Namespaces) == 0 || len(rule.Instances) == 0 {
+			return fmt.Errorf("rule %d: namespaces and instances are required", i)
+		}
+		if err := validatePatterns(rule.Namespaces); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if err := validatePatterns(rule.Instances); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if rule.MaxAccess == "" {
+			continue
+		}
+		if !contains(accessLevelServices, rule.Service) {
+			return fmt.Errorf("rule %d: service %s has no access levels", i, rule.Service)
+		}
+		if !contains(accessLevels, rule.MaxAccess) {
+			return fmt.Errorf("rule %d: unknown access level %q, must be one of %s", i, rule.MaxAccess, strings.Join(accessLevels, ", "))
+		}
+	}
+	return nil
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (p Policy) Authorize(namespace, service, instance, access string) error {
+	if !contains(accessLevelServices, service) {
+		access = ""
+	} else if access == "" {
+		access = "read"
+	}
+	restricted := false
+	denied := DeniedError{
+		Namespace: namespace,
+		Service:   service,
+		Instance:  instance,
+		Access:    access,
+	}
+	for _, rule := range p.Rules {
+		if rule.Service != service {
+			continue
+		}
+		restricted = true
+		if !rule.matches(namespace, instance) {
+			continue
+		}
+		if allowsAccess(rule.MaxAccess, access) {
+			return nil
+		}
+		denied.MaxAccess = rule.MaxAccess
+	}
+	if !restricted {
+		return nil
+	}
+	return denied
+}
+
+func (r Rule) matches(namespace, instance string) bool {
+	if !matchesAny(r.Namespaces, namespace) {
+		return false
+	}
+	instances := make([]string, 0, len(r.Instances))
+	for _, pattern := range r.Instances {
+		instances = append(instances, strings.ReplaceAll(pattern, namespacePlaceholder, namespace))
+	}
+	return matchesAny(instances, instance)
+}
+
+func allowsAccess(maxAccess, access string) bool {
+	if maxAccess == "" || maxAccess == "admin" || access == "" {
+		return true
+	}
+	if maxAccess == "readwrite" {
+		return access == "read" || access == "write" || access == "readwrite"
+	}
+	return access == maxAccess
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Check consults the authorizer before credentials for the instance are provisioned, and records denials on the application
+func Check(authorizer Authorizer, application *aiven_nais_io_v1.AivenApplication, service, instance, access string, logger log.FieldLogger) error {
+	err := authorizer.Authorize(application.GetNamespace(), service, instance, access)
+	if err == nil {
+		return nil
+	}
+	var denied DeniedError
+	if errors.As(err, &denied) {
+		utils.PolicyFail("Authorize", application, err, logger)
+	} else {
+		utils.LocalFail("Authorize", application, err, logger)
+	}
+	return err
+}
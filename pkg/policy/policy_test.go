@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/nais/aivenator/pkg/utils"
+)
+
+var testPolicy = Policy{
+	Rules: []Rule{
+		{Namespaces: []string{"*"}, Service: OpenSearch, Instances: []string{"opensearch-{namespace}-*"}},
+		{Namespaces: []string{"team-a"}, Service: OpenSearch, Instances: []string{"opensearch-shared"}, MaxAccess: "read"},
+		{Namespaces: []string{"team-b"}, Service: OpenSearch, Instances: []string{"opensearch-shared"}, MaxAccess: "readwrite"},
+		{Namespaces: []string{"team-*"}, Service: Kafka, Instances: []string{"nav-dev"}},
+	},
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespace     string
+		service       string
+		instance      string
+		access        string
+		wantDenied    bool
+		wantMaxAccess string
+	}{
+		{name: "own instance", namespace: "team-a", service: OpenSearch, instance: "opensearch-team-a-logs", access: "admin"},
+		{name: "other team's instance", namespace: "team-a", service: OpenSearch, instance: "opensearch-team-b-logs", access: "read", wantDenied: true},
+		{name: "shared instance within max access", namespace: "team-a", service: OpenSearch, instance: "opensearch-shared", access: "read"},
+		{name: "shared instance with empty access", namespace: "team-a", service: OpenSearch, instance: "opensearch-shared"},
+		{name: "shared instance above max access", namespace: "team-a", service: OpenSearch, instance: "opensearch-shared", access: "write", wantDenied: true, wantMaxAccess: "read"},
+		{name: "readwrite allows write", namespace: "team-b", service: OpenSearch, instance: "opensearch-shared", access: "write"},
+		{name: "readwrite does not allow admin", namespace: "team-b", service: OpenSearch, instance: "opensearch-shared", access: "admin", wantDenied: true, wantMaxAccess: "readwrite"},
+		{name: "allowed pool", namespace: "team-c", service: Kafka, instance: "nav-dev"},
+		{name: "other pool", namespace: "team-c", service: Kafka, instance: "nav-prod", wantDenied: true},
+		{name: "service without rules", namespace: "team-c", service: InfluxDB, instance: "influx-team-d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testPolicy.Authorize(tt.namespace, tt.service, tt.instance, tt.access)
+			if !tt.wantDenied {
+				assert.NoError(t, err)
+				return
+			}
+			var denied DeniedError
+			assert.ErrorAs(t, err, &denied)
+			assert.ErrorIs(t, err, utils.UnrecoverableError)
+			assert.Equal(t, tt.wantMaxAccess, denied.MaxAccess)
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	assert.NoError(t, testPolicy.Validate())
+
+	invalid := []Rule{
+		{Namespaces: []string{"*"}, Service: "postgres", Instances: []string{"*"}},
+		{Namespaces: []string{"*"}, Service: OpenSearch},
+		{Namespaces: []string{"["}, Service: OpenSearch, Instances: []string{"*"}},
+		{Namespaces: []string{"*"}, Service: OpenSearch, Instances: []string{"*"}, MaxAccess: "owner"},
+		{Namespaces: []string{"*"}, Service: Kafka, Instances: []string{"*"}, MaxAccess: "read"},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, Policy{Rules: []Rule{rule}}.Validate(), "%+v", rule)
+	}
+}
+
+func TestFileAuthorizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	authorizer := NewFileAuthorizer(path)
+
+	assert.Error(t, authorizer.Authorize("team-a", OpenSearch, "opensearch-team-a", "read"))
+
+	write := func(content string, modTime time.Time) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	now := time.Now()
+	write(`
+rules:
+  - namespaces: ["team-a"]
+    service: opensearch
+    instances: ["opensearch-team-a"]
+    maxAccess: read
+`, now)
+	assert.NoError(t, authorizer.Authorize("team-a", OpenSearch, "opensearch-team-a", "read"))
+	assert.Error(t, authorizer.Authorize("team-a", OpenSearch, "opensearch-team-a", "admin"))
+
+	write(`
+rules:
+  - namespaces: ["team-a"]
+    service: opensearch
+    instances: ["opensearch-team-a"]
+    maxAccess: admin
+`, now.Add(time.Minute))
+	assert.NoError(t, authorizer.Authorize("team-a", OpenSearch, "opensearch-team-a", "admin"))
+
+	write(`
+rules:
+  - namespaces: ["team-a"]
+    service: opensearch
+    instance: ["opensearch-team-a"]
+`, now.Add(2*time.Minute))
+	err := authorizer.Authorize("team-a", OpenSearch, "opensearch-team-a", "admin")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, utils.UnrecoverableError))
+}
+
+func TestCheck(t *testing.T) {
+	logger := log.New()
+	application := aiven_nais_io_v1.NewAivenApplicationBuilder("app", "team-a").Build()
+
+	assert.NoError(t, Check(testPolicy, &application, OpenSearch, "opensearch-team-a-logs", "read", logger))
+	assert.Nil(t, application.Status.GetConditionOfType(utils.AivenApplicationPolicyDenied))
+
+	err := Check(testPolicy, &application, OpenSearch, "opensearch-team-b-logs", "read", logger)
+	assert.ErrorIs(t, err, utils.UnrecoverableError)
+	condition := application.Status.GetConditionOfType(utils.AivenApplicationPolicyDenied)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, v1.ConditionTrue, condition.Status)
+		assert.Contains(t, condition.Message, "namespace team-a is not allowed to use opensearch instance opensearch-team-b-logs")
+	}
+}
@@ -63,3 +63,17 @@ func LocalFail(operation string, application *aiven_nais_io_v1.AivenApplication,
 		Message: message.Error(),
 	}, aiven_nais_io_v1.AivenApplicationSucceeded)
 }
+
+// AivenApplicationPolicyDenied is set when the access policy does not allow the application what it asks for
+const AivenApplicationPolicyDenied aiven_nais_io_v1.AivenApplicationConditionType = "PolicyDenied"
+
+func PolicyFail(operation string, application *aiven_nais_io_v1.AivenApplication, err error, logger logrus.FieldLogger) {
+	message := fmt.Errorf("operation %s denied by access policy: %s", operation, err)
+	logger.Error(message)
+	application.Status.AddCondition(aiven_nais_io_v1.AivenApplicationCondition{
+		Type:    AivenApplicationPolicyDenied,
+		Status:  v1.ConditionTrue,
+		Reason:  operation,
+		Message: message.Error(),
+	}, aiven_nais_io_v1.AivenApplicationSucceeded)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/aivenator/constants"
+	"github.com/nais/aivenator/pkg/metrics"
+)
+
+// MaxServiceUserNameLength is the longest service user name Aiven accepts
+const MaxServiceUserNameLength = 64
+
+const serviceUserNameHashLength = 8
+
+// ApplicationServiceUserName names the service user of an application on a service shared by many namespaces.
+// Kubernetes names cannot contain underscores, so joining with one keeps the names of different applications apart.
+// Names too long for Aiven are shortened, and end with a hash of the namespace and application to stay unique.
+func ApplicationServiceUserName(namespace, application, suffix string) string {
+	name := fmt.Sprintf("%s_%s", namespace, application)
+	if len(name)+len(suffix) <= MaxServiceUserNameLength {
+		return name + suffix
+	}
+	sum := sha256.Sum256([]byte(namespace + "/" + application))
+	hash := hex.EncodeToString(sum[:])[:serviceUserNameHashLength]
+	return fmt.Sprintf("%s_%s%s", name[:MaxServiceUserNameLength-len(suffix)-len(hash)-1], hash, suffix)
+}
+
+// ServiceUserInUse finds other secrets made by aivenator, in any namespace, with all the given annotations
+func ServiceUserInUse(ctx context.Context, secrets client.Reader, secret *v1.Secret, annotations map[string]string) (bool, error) {
+	var list v1.SecretList
+	err := metrics.ObserveKubernetesLatency("Secret_List", func() error {
+		return secrets.List(ctx, &list, client.MatchingLabels{
+			constants.SecretTypeLabel: constants.AivenatorSecretType,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, other := range list.Items {
+		if other.GetNamespace() == secret.GetNamespace() && other.GetName() == secret.GetName() {
+			continue
+		}
+		if hasAnnotations(other.GetAnnotations(), annotations) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasAnnotations(annotations, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if annotations[key] != value {
+			return false
+		}
+	}
+	return true
+}